@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"assignment2/logging"
+	"assignment2/problem"
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type contextKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext returns the user stored by Middleware.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(contextKey{}).(User)
+	return user, ok
+}
+
+// Middleware rejects requests without a valid "Authorization: Bearer" token
+// and stores the authenticated user in the request context.
+func Middleware(tokens *Tokens) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				problem.Write(w, problem.New(http.StatusUnauthorized, "missing_token", "Missing bearer token"))
+				return
+			}
+
+			user, err := tokens.Verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				problem.Write(w, problem.New(http.StatusUnauthorized, "invalid_token", err.Error()))
+				return
+			}
+
+			logging.SetUserID(r.Context(), user.UserID)
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
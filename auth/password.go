@@ -0,0 +1,44 @@
+// Package auth implements password hashing, signed access tokens and the
+// router middleware that authenticates API requests.
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Passwords accepted at registration are at least MinPasswordLength
+// characters long. MaxPasswordLength is in bytes, the most bcrypt hashes.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// dummyHash is compared against when a login names an unknown user so that
+// the response time does not reveal which usernames exist.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash stored in Users.PasswordHash.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword returns ErrInvalidCredentials unless password matches hash.
+// An empty hash is treated as an unknown user.
+func CheckPassword(hash string, password string) error {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
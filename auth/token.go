@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// User is the authenticated user attached to a request.
+type User struct {
+	UserID   int64
+	Username string
+}
+
+type claims struct {
+	Username string `json:"username"`
+	jwt.RegisteredClaims
+}
+
+// Tokens issues and verifies HS256 signed JWT access tokens.
+type Tokens struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokens(secret []byte, ttl time.Duration) *Tokens {
+	return &Tokens{secret: secret, ttl: ttl}
+}
+
+// Issue returns a signed token for the user and the time it expires.
+func (t *Tokens) Issue(user User) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Username: user.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.UserID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	signed, err := token.SignedString(t.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("signing token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// Verify checks the token signature and expiry and returns its user.
+func (t *Tokens) Verify(token string) (User, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (any, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return User{}, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return User{}, ErrInvalidToken
+	}
+	return User{UserID: userID, Username: c.Username}, nil
+}
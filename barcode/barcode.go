@@ -0,0 +1,47 @@
+// Package barcode validates the GTIN family of product barcodes: EAN-8,
+// UPC-A, EAN-13 and GTIN-14.
+package barcode
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("barcode must be an EAN-8, UPC-A, EAN-13 or GTIN-14 with a valid check digit")
+
+// Normalize validates code and returns it as a 14 digit GTIN, so that the
+// same product scanned as UPC-A or EAN-13 has a single representation.
+// Spaces and hyphens are ignored.
+func Normalize(code string) (string, error) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return "", ErrInvalid
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return "", ErrInvalid
+		}
+	}
+
+	gtin := strings.Repeat("0", 14-len(code)) + code
+	if checkDigit(gtin[:13]) != gtin[13] {
+		return "", ErrInvalid
+	}
+	return gtin, nil
+}
+
+// checkDigit computes the GS1 check digit of the first 13 digits of a
+// GTIN-14: weights alternate 3 and 1 starting from the leftmost digit.
+func checkDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		weight := 1
+		if i%2 == 0 {
+			weight = 3
+		}
+		sum += int(digits[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,67 @@
+package barcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"96385074", "00000096385074"},       // EAN-8
+		{"036000291452", "00036000291452"},   // UPC-A
+		{"4006381333931", "04006381333931"},  // EAN-13
+		{"0036000291452", "00036000291452"},  // UPC-A written as EAN-13
+		{"10614141000415", "10614141000415"}, // GTIN-14
+		{"400-6381 333931", "04006381333931"},
+		// a check digit of zero
+		{"5000112637939", "05000112637939"},
+	}
+	for _, test := range tests {
+		got, err := Normalize(test.code)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", test.code, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.code, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	for _, code := range []string{
+		"",
+		"1234567",         // too short
+		"123456789",       // no GTIN has 9 digits
+		"400638133393112", // too long
+		"4006381333932",   // wrong check digit
+		"036000291453",
+		"96385075",
+		"40063813339a1",
+		"+4006381333931",
+	} {
+		if got, err := Normalize(code); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Normalize(%q) = %q, %v, want ErrInvalid", code, got, err)
+		}
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	// every single digit error changes the check digit
+	const gtin = "0400638133393"
+	want := checkDigit(gtin)
+	for i := 0; i < len(gtin); i++ {
+		for d := byte('0'); d <= '9'; d++ {
+			if d == gtin[i] {
+				continue
+			}
+			changed := gtin[:i] + string(d) + gtin[i+1:]
+			if checkDigit(changed) == want {
+				t.Errorf("checkDigit(%s) = checkDigit(%s)", changed, gtin)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package catalog defines the canonical nutrients the API accepts, together
+// with their units, categories and the aliases clients may use for them.
+// The stores are seeded from Nutrients on startup so every Nutrients row
+// written through the API is one of these.
+package catalog
+
+import (
+	"strings"
+)
+
+// Categories group the catalog for display.
+const (
+	CategoryEnergy  = "energy"
+	CategoryMacro   = "macro"
+	CategoryVitamin = "vitamin"
+	CategoryMineral = "mineral"
+)
+
+// Units the catalog amounts are expressed in.
+const (
+	UnitKcal      = "kcal"
+	UnitGram      = "g"
+	UnitMilligram = "mg"
+	UnitMicrogram = "µg"
+)
+
+// Nutrient is a canonical nutrient. Amounts of it are always stored in Unit
+// per 100 g of an ingredient.
+type Nutrient struct {
+	Name     string
+	Unit     string
+	Category string
+	Aliases  []string
+}
+
+var Nutrients = []Nutrient{
+	{Name: "Energy", Unit: UnitKcal, Category: CategoryEnergy, Aliases: []string{"calories", "calorie", "kcal", "energy kcal"}},
+
+	{Name: "Protein", Unit: UnitGram, Category: CategoryMacro, Aliases: []string{"proteins"}},
+	{Name: "Carbohydrate", Unit: UnitGram, Category: CategoryMacro, Aliases: []string{"carbohydrates", "carbs", "carb", "total carbohydrate", "total carbohydrates"}},
+	{Name: "Sugars", Unit: UnitGram, Category: CategoryMacro, Aliases: []string{"sugar", "total sugars"}},
+	{Name: "Fiber", Unit: UnitGram, Category: CategoryMacro, Aliases: []string{"fibre", "dietary fiber", "dietary fibre"}},
+	{Name: "Fat", Unit: UnitGram, Category: CategoryMacro, Aliases: []string{"fats", "total fat", "total lipid", "lipids"}},
+	{Name: "Saturated Fat", Unit: UnitGram, Category: CategoryMacro, Aliases: []string{"saturated fats", "saturates", "sat fat"}},
+
+	{Name: "Vitamin A", Unit: UnitMicrogram, Category: CategoryVitamin, Aliases: []string{"retinol", "vitamin a rae"}},
+	{Name: "Thiamin", Unit: UnitMilligram, Category: CategoryVitamin, Aliases: []string{"thiamine", "vitamin b1"}},
+	{Name: "Riboflavin", Unit: UnitMilligram, Category: CategoryVitamin, Aliases: []string{"vitamin b2"}},
+	{Name: "Niacin", Unit: UnitMilligram, Category: CategoryVitamin, Aliases: []string{"vitamin b3"}},
+	{Name: "Vitamin B6", Unit: UnitMilligram, Category: CategoryVitamin, Aliases: []string{"pyridoxine"}},
+	{Name: "Folate", Unit: UnitMicrogram, Category: CategoryVitamin, Aliases: []string{"folic acid", "vitamin b9"}},
+	{Name: "Vitamin B12", Unit: UnitMicrogram, Category: CategoryVitamin, Aliases: []string{"cobalamin"}},
+	{Name: "Vitamin C", Unit: UnitMilligram, Category: CategoryVitamin, Aliases: []string{"ascorbic acid"}},
+	{Name: "Vitamin D", Unit: UnitMicrogram, Category: CategoryVitamin, Aliases: []string{"calciferol"}},
+	{Name: "Vitamin E", Unit: UnitMilligram, Category: CategoryVitamin, Aliases: []string{"tocopherol"}},
+	{Name: "Vitamin K", Unit: UnitMicrogram, Category: CategoryVitamin, Aliases: []string{"phylloquinone"}},
+
+	{Name: "Calcium", Unit: UnitMilligram, Category: CategoryMineral},
+	{Name: "Iron", Unit: UnitMilligram, Category: CategoryMineral},
+	{Name: "Magnesium", Unit: UnitMilligram, Category: CategoryMineral},
+	{Name: "Phosphorus", Unit: UnitMilligram, Category: CategoryMineral},
+	{Name: "Potassium", Unit: UnitMilligram, Category: CategoryMineral},
+	{Name: "Sodium", Unit: UnitMilligram, Category: CategoryMineral, Aliases: []string{"sodium na"}},
+	{Name: "Zinc", Unit: UnitMilligram, Category: CategoryMineral},
+	{Name: "Selenium", Unit: UnitMicrogram, Category: CategoryMineral},
+}
+
+// unitAliases maps the unit spellings accepted in names such as
+// "Protein (g)" to catalog units.
+var unitAliases = map[string]string{
+	"kcal": UnitKcal,
+	"cal":  UnitKcal,
+	"g":    UnitGram,
+	"mg":   UnitMilligram,
+	"µg":   UnitMicrogram,
+	"μg":   UnitMicrogram,
+	"ug":   UnitMicrogram,
+	"mcg":  UnitMicrogram,
+}
+
+var byAlias = make(map[string]Nutrient)
+
+func init() {
+	for _, nutrient := range Nutrients {
+		byAlias[normalize(nutrient.Name)] = nutrient
+		for _, alias := range nutrient.Aliases {
+			byAlias[normalize(alias)] = nutrient
+		}
+	}
+}
+
+// Lookup resolves a client supplied nutrient name to its canonical nutrient.
+// Matching ignores case, spacing, underscores and hyphens. A trailing unit in
+// parentheses, as in "Sodium (mg)", is accepted only when it is the unit the
+// catalog uses for that nutrient.
+func Lookup(name string) (Nutrient, bool) {
+	name = strings.TrimSpace(name)
+	var unit string
+	if open := strings.LastIndex(name, "("); open > 0 && strings.HasSuffix(name, ")") {
+		unit = name[open+1 : len(name)-1]
+		name = name[:open]
+	}
+
+	nutrient, ok := byAlias[normalize(name)]
+	if !ok {
+		return Nutrient{}, false
+	}
+	if parsed, _ := ParseUnit(unit); unit != "" && parsed != nutrient.Unit {
+		return Nutrient{}, false
+	}
+	return nutrient, true
+}
+
+// ConvertToPerHundredGrams converts an amount per serving, as printed on food
+// labels, to the amount per 100 g the catalog uses. It is shared by the
+// ingredient handlers and the importers.
+func ConvertToPerHundredGrams(amount float64, servingSizeInGrams float64) float64 {
+	return (amount / servingSizeInGrams) * 100
+}
+
+// ParseUnit returns the catalog unit for a unit spelling such as "MG" or
+// "mcg".
+func ParseUnit(unit string) (string, bool) {
+	parsed, ok := unitAliases[strings.ToLower(strings.TrimSpace(unit))]
+	return parsed, ok
+}
+
+// gramsPerUnit relates the mass units to each other.
+var gramsPerUnit = map[string]float64{
+	UnitGram:      1,
+	UnitMilligram: 1e-3,
+	UnitMicrogram: 1e-6,
+}
+
+// Convert converts amount between two catalog units. Only mass units can be
+// converted into each other.
+func Convert(amount float64, from string, to string) (float64, bool) {
+	if from == to {
+		return amount, true
+	}
+	fromGrams, ok := gramsPerUnit[from]
+	if !ok {
+		return 0, false
+	}
+	toGrams, ok := gramsPerUnit[to]
+	if !ok {
+		return 0, false
+	}
+	return amount * fromGrams / toGrams, true
+}
+
+func normalize(name string) string {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("_", " ", "-", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
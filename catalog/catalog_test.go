@@ -0,0 +1,81 @@
+package catalog
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Protein", "Protein"},
+		{"  proteins ", "Protein"},
+		{"CALORIES", "Energy"},
+		{"total_carbohydrate", "Carbohydrate"},
+		{"saturated-fats", "Saturated Fat"},
+		{"Sodium (mg)", "Sodium"},
+		{"Vitamin B12 (mcg)", "Vitamin B12"},
+		{"Protein(g)", "Protein"},
+	}
+	for _, test := range tests {
+		got, ok := Lookup(test.name)
+		if !ok || got.Name != test.want {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", test.name, got.Name, ok, test.want)
+		}
+	}
+}
+
+func TestLookupRejects(t *testing.T) {
+	for _, name := range []string{"", "Unobtainium", "Sodium (g)", "Protein (furlongs)", "(g)"} {
+		if got, ok := Lookup(name); ok {
+			t.Errorf("Lookup(%q) = %q, want no match", name, got.Name)
+		}
+	}
+}
+
+func TestAliasesAreUnique(t *testing.T) {
+	seen := make(map[string]string)
+	for _, nutrient := range Nutrients {
+		for _, name := range append([]string{nutrient.Name}, nutrient.Aliases...) {
+			key := normalize(name)
+			if other, ok := seen[key]; ok && other != nutrient.Name {
+				t.Errorf("%q names both %s and %s", name, other, nutrient.Name)
+			}
+			seen[key] = nutrient.Name
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		from, to string
+		want     float64
+		ok       bool
+	}{
+		{1.5, UnitGram, UnitMilligram, 1500, true},
+		{250, UnitMicrogram, UnitMilligram, 0.25, true},
+		{100, UnitKcal, UnitKcal, 100, true},
+		{100, UnitKcal, UnitGram, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := Convert(test.amount, test.from, test.to)
+		if ok != test.ok || got != test.want {
+			t.Errorf("Convert(%v, %s, %s) = %v, %v, want %v, %v", test.amount, test.from, test.to, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestConvertToPerHundredGrams(t *testing.T) {
+	tests := []struct {
+		amount, serving, want float64
+	}{
+		{2.5, 25, 10},
+		{30, 100, 30},
+		{0.01, 50, 0.02},
+	}
+	for _, test := range tests {
+		if got := ConvertToPerHundredGrams(test.amount, test.serving); got != test.want {
+			t.Errorf("ConvertToPerHundredGrams(%v, %v) = %v, want %v", test.amount, test.serving, got, test.want)
+		}
+	}
+}
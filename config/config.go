@@ -0,0 +1,228 @@
+// Package config loads the server configuration. Each setting has a default
+// that can be overridden, from lowest to highest precedence, by a YAML or TOML
+// file, by an environment variable and by a command line flag. The file is
+// named by the -config flag or the CONFIG_FILE variable and its format is
+// chosen by its extension.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// Storage is postgres or memory.
+	Storage   string         `yaml:"storage" toml:"storage"`
+	ImportDir string         `yaml:"import_dir" toml:"import_dir"`
+	Server    ServerConfig   `yaml:"server" toml:"server"`
+	TLS       TLSConfig      `yaml:"tls" toml:"tls"`
+	Database  DatabaseConfig `yaml:"database" toml:"database"`
+	Auth      AuthConfig     `yaml:"auth" toml:"auth"`
+	Log       LogConfig      `yaml:"log" toml:"log"`
+	Tracing   TracingConfig  `yaml:"tracing" toml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port" toml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout bounds the wait for in-flight requests on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// DrainDelay keeps serving, with failing probes, for a while after a
+	// shutdown signal so that load balancers can take the server out first.
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+}
+
+// TLSConfig enables HTTPS when both files are set.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+}
+
+// Enabled reports whether the server should serve HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host" toml:"host"`
+	Port            int           `yaml:"port" toml:"port"`
+	User            string        `yaml:"user" toml:"user"`
+	Password        string        `yaml:"password" toml:"password"`
+	Name            string        `yaml:"name" toml:"name"`
+	SSLMode         string        `yaml:"sslmode" toml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	// ConnectTimeout bounds the connection attempts at startup, made while
+	// the database may still be starting.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+}
+
+type AuthConfig struct {
+	// Secret signs the bearer tokens. When empty a random secret is used and
+	// tokens do not survive a restart.
+	Secret   string        `yaml:"secret" toml:"secret"`
+	TokenTTL time.Duration `yaml:"token_ttl" toml:"token_ttl"`
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level" toml:"level"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string `yaml:"exporter" toml:"exporter"`
+}
+
+// Default returns the configuration used for settings that are not set
+// anywhere else.
+func Default() *Config {
+	return &Config{
+		Storage: "postgres",
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    20,
+			MaxIdleConns:    20,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  time.Minute,
+		},
+		Auth:    AuthConfig{TokenTTL: 24 * time.Hour},
+		Log:     LogConfig{Level: "info"},
+		Tracing: TracingConfig{Exporter: "none"},
+	}
+}
+
+// Load builds the configuration from the defaults, the configuration file,
+// the environment and the flags in args, and validates it. name is the program
+// name used in flag errors. The returned bool is true when -print-config was
+// given.
+func Load(name string, args []string) (*Config, bool, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	printConfig := flags.Bool("print-config", false, "print the effective configuration and exit")
+	load := AddFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if flags.NArg() > 0 {
+		return nil, false, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	cfg, err := load()
+	if err != nil {
+		return nil, false, err
+	}
+	return cfg, *printConfig, nil
+}
+
+// AddFlags adds -config and the flag of every setting to flags, for the
+// subcommands that have flags and arguments of their own. Once flags is
+// parsed, the returned function builds and validates the configuration like
+// Load.
+func AddFlags(flags *flag.FlagSet) func() (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration `file`")
+	// flag values are only recorded here and applied after the file and the
+	// environment, which they override
+	raw := make(map[string]*rawValue)
+	for _, s := range settings {
+		raw[s.flag] = &rawValue{value: s.get()}
+		flags.Var(raw[s.flag], s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+
+	return func() (*Config, error) {
+		if *configFile != "" {
+			if err := cfg.loadFile(*configFile); err != nil {
+				return nil, err
+			}
+		}
+		for _, s := range settings {
+			if value, ok := os.LookupEnv(s.env); ok {
+				if err := s.set(value); err != nil {
+					return nil, fmt.Errorf("invalid %s %q: %w", s.env, value, err)
+				}
+			}
+		}
+		for _, s := range settings {
+			if value := raw[s.flag]; value.set {
+				if err := s.set(value.value); err != nil {
+					return nil, fmt.Errorf("invalid -%s %q: %w", s.flag, value.value, err)
+				}
+			}
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+}
+
+func (c *Config) loadFile(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(contents)))
+		dec.KnownFields(true)
+		err = dec.Decode(c)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".toml":
+		var meta toml.MetaData
+		meta, err = toml.Decode(string(contents), c)
+		if err == nil && len(meta.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown settings %v", meta.Undecoded())
+		}
+	default:
+		return fmt.Errorf("%s: expected a .yaml, .yml or .toml file", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// rawValue records a flag for Load to apply later.
+type rawValue struct {
+	value string
+	set   bool
+}
+
+func (v *rawValue) String() string {
+	return v.value
+}
+
+func (v *rawValue) Set(value string) error {
+	v.value = value
+	v.set = true
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfigFile writes a YAML configuration file and returns its path.
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	path := writeConfigFile(t, "database:\n  host: file-host\n  port: 6543\n  name: file-name\n  user: app\n")
+	t.Setenv("DB_PORT", "7654")
+	t.Setenv("DB_NAME", "env-name")
+
+	cfg, printConfig, err := Load("test", []string{"-config", path, "-db-name", "flag-name", "-print-config"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !printConfig {
+		t.Error("Load did not report -print-config")
+	}
+	// the file beats the default, the environment the file and a flag both
+	if db := cfg.Database; db.Host != "file-host" || db.Port != 7654 || db.Name != "flag-name" {
+		t.Errorf("Database = %+v, want host file-host, port 7654 and name flag-name", db)
+	}
+
+	if _, _, err := Load("test", []string{"up"}); err == nil {
+		t.Error("Load accepted an argument")
+	}
+}
+
+func TestAddFlags(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "database:\n  host: file-host\n  user: app\n"))
+
+	// a subcommand with a flag and arguments of its own
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	source := flags.String("source", "fdc", "format of the dump")
+	load := AddFlags(flags)
+	if err := flags.Parse([]string{"-source", "off", "-db-name", "flag-name", "products.jsonl"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	cfg, err := load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if *source != "off" || !reflect.DeepEqual(flags.Args(), []string{"products.jsonl"}) {
+		t.Errorf("subcommand got -source %q and arguments %q", *source, flags.Args())
+	}
+	if cfg.Database.Host != "file-host" || cfg.Database.Name != "flag-name" {
+		t.Errorf("Database = %+v, want the host of the file and the name of the flag", cfg.Database)
+	}
+
+	flags = flag.NewFlagSet("migrate", flag.ContinueOnError)
+	load = AddFlags(flags)
+	if err := flags.Parse([]string{"-db-port", "0", "up"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := load(); err == nil {
+		t.Error("load accepted an invalid configuration")
+	}
+}
+
+func TestValidateAuthSecret(t *testing.T) {
+	tests := []struct {
+		secret string
+		ok     bool
+	}{
+		{"", true},
+		{"0123456789abcdef0123456789abcdef", true},
+		{"0123456789abcdef0123456789abcde", false},
+		{placeholderSecret, false},
+	}
+	for _, test := range tests {
+		cfg := Default()
+		cfg.Storage = "memory"
+		cfg.Auth.Secret = test.secret
+		if err := cfg.Validate(); (err == nil) != test.ok {
+			t.Errorf("Validate with secret %q = %v, want ok %v", test.secret, err, test.ok)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"assignment2/logging"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// setting binds one configuration field to its flag and environment variable.
+type setting struct {
+	flag   string
+	env    string
+	usage  string
+	secret bool
+	value  any
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{flag: "storage", env: "STORAGE", usage: "storage backend, postgres or memory", value: &c.Storage},
+		{flag: "import-dir", env: "IMPORT_DIR", usage: "directory POST /api/imports reads dumps from, empty to disable", value: &c.ImportDir},
+
+		{flag: "port", env: "APP_PORT", usage: "HTTP port", value: &c.Server.Port},
+		{flag: "read-timeout", env: "READ_TIMEOUT", usage: "maximum duration for reading a request", value: &c.Server.ReadTimeout},
+		{flag: "read-header-timeout", env: "READ_HEADER_TIMEOUT", usage: "maximum duration for reading request headers", value: &c.Server.ReadHeaderTimeout},
+		{flag: "write-timeout", env: "WRITE_TIMEOUT", usage: "maximum duration for writing a response", value: &c.Server.WriteTimeout},
+		{flag: "idle-timeout", env: "IDLE_TIMEOUT", usage: "how long keep-alive connections stay open between requests", value: &c.Server.IdleTimeout},
+		{flag: "shutdown-timeout", env: "SHUTDOWN_TIMEOUT", usage: "how long to wait for in-flight requests on shutdown", value: &c.Server.ShutdownTimeout},
+		{flag: "drain-delay", env: "DRAIN_DELAY", usage: "how long to keep serving with failing probes after a shutdown signal", value: &c.Server.DrainDelay},
+
+		{flag: "tls-cert", env: "TLS_CERT_FILE", usage: "TLS certificate file, enables HTTPS together with -tls-key", value: &c.TLS.CertFile},
+		{flag: "tls-key", env: "TLS_KEY_FILE", usage: "TLS private key file", value: &c.TLS.KeyFile},
+
+		{flag: "db-host", env: "DB_HOSTNAME", usage: "Postgres host", value: &c.Database.Host},
+		{flag: "db-port", env: "DB_PORT", usage: "Postgres port", value: &c.Database.Port},
+		{flag: "db-user", env: "DB_USERNAME", usage: "Postgres user", value: &c.Database.User},
+		{flag: "db-password", env: "DB_PASSWORD", usage: "Postgres password", secret: true, value: &c.Database.Password},
+		{flag: "db-name", env: "DB_NAME", usage: "Postgres database", value: &c.Database.Name},
+		{flag: "db-sslmode", env: "DB_SSLMODE", usage: "Postgres sslmode", value: &c.Database.SSLMode},
+		{flag: "db-max-open-conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum open connections, 0 for no limit", value: &c.Database.MaxOpenConns},
+		{flag: "db-max-idle-conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum idle connections", value: &c.Database.MaxIdleConns},
+		{flag: "db-conn-max-lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "maximum lifetime of a connection, 0 for no limit", value: &c.Database.ConnMaxLifetime},
+		{flag: "db-connect-timeout", env: "DB_CONNECT_TIMEOUT", usage: "how long to keep trying to reach the database at startup", value: &c.Database.ConnectTimeout},
+		{flag: "db-conn-max-idle-time", env: "DB_CONN_MAX_IDLE_TIME", usage: "maximum idle time of a connection, 0 for no limit", value: &c.Database.ConnMaxIdleTime},
+
+		{flag: "auth-secret", env: "AUTH_SECRET", usage: "secret signing the bearer tokens, random when empty", secret: true, value: &c.Auth.Secret},
+		{flag: "token-ttl", env: "AUTH_TOKEN_TTL", usage: "lifetime of issued bearer tokens", value: &c.Auth.TokenTTL},
+
+		{flag: "log-level", env: "LOG_LEVEL", usage: "debug, info, warn or error", value: &c.Log.Level},
+		{flag: "trace-exporter", env: "TRACE_EXPORTER", usage: "none, stdout or otlp", value: &c.Tracing.Exporter},
+	}
+}
+
+func (s setting) set(raw string) error {
+	switch value := s.value.(type) {
+	case *string:
+		*value = raw
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("expected an integer")
+		}
+		*value = n
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("expected a duration such as 30s or 5m")
+		}
+		*value = d
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %T", s.value))
+	}
+	return nil
+}
+
+func (s setting) get() string {
+	switch value := s.value.(type) {
+	case *string:
+		return *value
+	case *int:
+		return strconv.Itoa(*value)
+	case *time.Duration:
+		return value.String()
+	}
+	return fmt.Sprint(s.value)
+}
+
+// Redacted returns the effective settings, named after their flags, with
+// secrets replaced by "[redacted]" when they are set.
+func (c *Config) Redacted() []slog.Attr {
+	var attrs []slog.Attr
+	for _, s := range c.settings() {
+		value := s.get()
+		if s.secret && value != "" {
+			value = "[redacted]"
+		}
+		attrs = append(attrs, slog.String(s.flag, value))
+	}
+	return attrs
+}
+
+// minSecretLength is the shortest accepted auth secret, in bytes. HS256 keys
+// should be at least as long as the hash.
+const minSecretLength = 32
+
+// placeholderSecret is the example secret once shipped in .env, which is
+// public and must never sign tokens.
+const placeholderSecret = "change_me_to_a_long_random_string"
+
+// Validate checks every setting and reports all invalid ones at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Storage == "postgres" || c.Storage == "memory", "storage must be postgres or memory, got %q", c.Storage)
+	if c.ImportDir != "" {
+		info, err := os.Stat(c.ImportDir)
+		check(err == nil && info.IsDir(), "import dir %q must be an existing directory", c.ImportDir)
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "port must be between 1 and 65535, got %d", c.Server.Port)
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read timeout", c.Server.ReadTimeout},
+		{"read header timeout", c.Server.ReadHeaderTimeout},
+		{"write timeout", c.Server.WriteTimeout},
+		{"idle timeout", c.Server.IdleTimeout},
+		{"shutdown timeout", c.Server.ShutdownTimeout},
+	} {
+		check(timeout.value > 0, "%s must be positive, got %s", timeout.name, timeout.value)
+	}
+	check(c.Server.DrainDelay >= 0, "drain delay must not be negative, got %s", c.Server.DrainDelay)
+
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "TLS needs both a certificate and a key file")
+	for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+		if file != "" {
+			_, err := os.Stat(file)
+			check(err == nil, "TLS file %q is not readable", file)
+		}
+	}
+
+	if c.Storage == "postgres" {
+		check(c.Database.Host != "", "database host must be set")
+		check(c.Database.Name != "", "database name must be set")
+		check(c.Database.User != "", "database user must be set")
+	}
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "database port must be between 1 and 65535, got %d", c.Database.Port)
+	check(oneOf(c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"), "database sslmode %q is not a Postgres sslmode", c.Database.SSLMode)
+	check(c.Database.MaxOpenConns >= 0, "max open connections must not be negative, got %d", c.Database.MaxOpenConns)
+	check(c.Database.MaxIdleConns >= 0, "max idle connections must not be negative, got %d", c.Database.MaxIdleConns)
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"max idle connections (%d) must not exceed max open connections (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	check(c.Database.ConnMaxLifetime >= 0, "connection max lifetime must not be negative, got %s", c.Database.ConnMaxLifetime)
+	check(c.Database.ConnectTimeout > 0, "database connect timeout must be positive, got %s", c.Database.ConnectTimeout)
+	check(c.Database.ConnMaxIdleTime >= 0, "connection max idle time must not be negative, got %s", c.Database.ConnMaxIdleTime)
+
+	if c.Auth.Secret != "" {
+		check(c.Auth.Secret != placeholderSecret, "auth secret is the placeholder from .env, set a random one")
+		check(len(c.Auth.Secret) >= minSecretLength, "auth secret must be at least %d bytes, got %d", minSecretLength, len(c.Auth.Secret))
+	}
+	check(c.Auth.TokenTTL > 0, "token TTL must be positive, got %s", c.Auth.TokenTTL)
+	_, err := logging.ParseLevel(c.Log.Level)
+	check(err == nil, "log level must be one of debug, info, warn or error, got %q", c.Log.Level)
+	check(oneOf(c.Tracing.Exporter, "none", "stdout", "otlp"), "trace exporter must be one of none, stdout or otlp, got %q", c.Tracing.Exporter)
+
+	return errors.Join(errs...)
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"assignment2/config"
+	"assignment2/migrations"
+	"assignment2/store"
+	"assignment2/tracing"
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
-	"os"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -14,17 +23,6 @@ const (
 	DBDriver = "postgres"
 )
 
-const USERS_TABLE_CREATE_SQL = `
-CREATE TABLE IF NOT EXISTS Users (
-    UserID SERIAL PRIMARY KEY,
-    Username VARCHAR(255) NOT NULL UNIQUE,
-    Email VARCHAR(255) NOT NULL UNIQUE,
-    PasswordHash VARCHAR(255) NOT NULL,
-    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-`
-
 type Ingredient struct {
 	IngredientID int
 	Name         string
@@ -32,15 +30,6 @@ type Ingredient struct {
 	UpdatedAt    time.Time
 }
 
-const INGREDIENTS_TABLE_CREATE_SQL = `
-CREATE TABLE IF NOT EXISTS Ingredients (
-    IngredientID SERIAL PRIMARY KEY,
-    Name VARCHAR(255) NOT NULL,
-    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-`
-
 type Nutrient struct {
 	NutrientID int
 	Name       string
@@ -48,26 +37,6 @@ type Nutrient struct {
 	UpdatedAt  time.Time
 }
 
-const NUTRIENTS_TABLE_CREATE_SQL = `
-CREATE TABLE IF NOT EXISTS Nutrients (
-    NutrientID SERIAL PRIMARY KEY,
-    Name VARCHAR(255) NOT NULL,
-    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-`
-
-const NUTRIENT_VALUES_TABLE_CREATE_SQL = `
-CREATE TABLE IF NOT EXISTS Nutrient_Values (
-    IngredientID INT NOT NULL,
-    NutrientID INT NOT NULL,
-    AmountPer100g NUMERIC(10,2) NOT NULL,
-    PRIMARY KEY (IngredientID, NutrientID),
-    FOREIGN KEY (IngredientID) REFERENCES Ingredients(IngredientID) ON UPDATE CASCADE ON DELETE CASCADE,
-    FOREIGN KEY (NutrientID) REFERENCES Nutrients(NutrientID) ON UPDATE CASCADE ON DELETE CASCADE
-);
-`
-
 type Meal struct {
 	MealID    int
 	UserID    int
@@ -77,118 +46,91 @@ type Meal struct {
 	UpdatedAt time.Time
 }
 
-// const MEALS_TABLE_CREATE_SQL = `
-// CREATE TABLE IF NOT EXISTS Meals (
-//
-//	    MealID SERIAL PRIMARY KEY,
-//		Name VARCHAR(255) NOT NULL,
-//	    UserID INT NOT NULL,
-//	    Date DATE NOT NULL,
-//	    Time TIME NOT NULL,
-//	    FOREIGN KEY (UserID) REFERENCES Users(UserID) ON UPDATE CASCADE,
-//	    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-//	    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
-//
-// );
-// `
-const MEALS_TABLE_CREATE_SQL = `
-CREATE TABLE IF NOT EXISTS Meals (
-    MealID SERIAL PRIMARY KEY,
-	Name VARCHAR(255) NOT NULL,
-    Date DATE NOT NULL,
-    Time TIME NOT NULL,
-    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-`
-
-const MEAL_INGREDIENTS_TABLE_CREATE_SQL = `
-CREATE TABLE IF NOT EXISTS Meal_Ingredients (
-    MealID INT NOT NULL,
-    IngredientID INT NOT NULL,
-    QuantityInGrams NUMERIC(10,2) NOT NULL,
-    PRIMARY KEY (MealID, IngredientID),
-    FOREIGN KEY (MealID) REFERENCES Meals(MealID) ON UPDATE CASCADE ON DELETE CASCADE,
-    FOREIGN KEY (IngredientID) REFERENCES Ingredients(IngredientID) ON UPDATE CASCADE ON DELETE CASCADE
-);
-`
-
-func initDB() *sql.DB {
-	db_username := os.Getenv("DB_USERNAME")
-	db_password := os.Getenv("DB_PASSWORD")
-	db_host := os.Getenv("DB_HOSTNAME")
-	db_port := os.Getenv("DB_PORT")
-	db_database := os.Getenv("DB_NAME")
-
-	connStr := "postgres://" + db_username + ":" + db_password + "@" + db_host + ":" + db_port + "/" + db_database + "?sslmode=disable"
-
-	db, err := sql.Open(DBDriver, connStr)
-	if err != nil {
-		log.Fatal(err)
+// openDB connects to Postgres and configures the connection pool.
+func openDB(cfg config.DatabaseConfig) *sql.DB {
+	connURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Name,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
 	}
 
-	// set max connections
-	db.SetMaxOpenConns(20)
-	// set max idle connections
-	db.SetMaxIdleConns(20)
-	// set max connection lifetime
-	db.SetConnMaxLifetime(5)
-	// set connection timeout
-	db.SetConnMaxIdleTime(5)
-
-	// check connection
-	err = db.Ping()
+	db, err := tracing.OpenDB(DBDriver, connURL.String())
 	if err != nil {
-		panic(err.Error())
+		log.Fatal(err)
 	}
 
-	log.Println("Database is ready!")
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	// create table if not exists
-	tx, err := db.Begin()
-	if err != nil {
+	if err := pingWithRetry(db, cfg.ConnectTimeout); err != nil {
 		log.Fatal(err)
 	}
 
-	_, err = tx.Exec(USERS_TABLE_CREATE_SQL)
-	if err != nil {
-		tx.Rollback()
-		log.Fatal(err)
-	}
+	log.Println("Database is ready!")
 
-	_, err = tx.Exec(INGREDIENTS_TABLE_CREATE_SQL)
-	if err != nil {
-		tx.Rollback()
-		log.Fatal(err)
-	}
+	return db
+}
 
-	_, err = tx.Exec(NUTRIENTS_TABLE_CREATE_SQL)
-	if err != nil {
-		tx.Rollback()
-		log.Fatal(err)
-	}
+// Backoff between the connection attempts of pingWithRetry.
+const (
+	initialPingDelay = 250 * time.Millisecond
+	maxPingDelay     = 10 * time.Second
+)
 
-	_, err = tx.Exec(NUTRIENT_VALUES_TABLE_CREATE_SQL)
-	if err != nil {
-		tx.Rollback()
-		log.Fatal(err)
+// pingWithRetry waits for the database to answer, for instance while its
+// container is still starting. The delay between attempts doubles up to
+// maxPingDelay, with jitter so that several instances do not retry in step.
+// Errors other than an unreachable database, such as a wrong password, are
+// returned at once, and so is the last error when the next attempt would
+// start after the timeout.
+func pingWithRetry(db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	deadline, _ := ctx.Deadline()
+	delay := initialPingDelay
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() == nil && !store.Unavailable(err) {
+			return fmt.Errorf("connecting to the database: %w", err)
+		}
+
+		// give up without announcing a retry that would start too late
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		if ctx.Err() != nil || time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("database not reachable after %d attempts in %s: %w", attempt, timeout, err)
+		}
+		log.Printf("Database is not reachable (attempt %d), retrying in %s: %v\n", attempt, wait.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %d attempts in %s: %w", attempt, timeout, err)
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, maxPingDelay)
 	}
+}
 
-	_, err = tx.Exec(MEALS_TABLE_CREATE_SQL)
+// initDB connects to Postgres and applies any pending schema migrations.
+func initDB(cfg config.DatabaseConfig) *sql.DB {
+	db := openDB(cfg)
+
+	migrator, err := migrations.New(db)
 	if err != nil {
-		tx.Rollback()
 		log.Fatal(err)
 	}
-
-	_, err = tx.Exec(MEAL_INGREDIENTS_TABLE_CREATE_SQL)
+	err = migrator.Up(context.Background())
 	if err != nil {
-		tx.Rollback()
 		log.Fatal(err)
 	}
 
-	tx.Commit()
-
-	log.Println("Database tables created!")
+	log.Println("Database schema is up to date!")
 
 	return db
 }
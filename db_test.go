@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// flakyConnector fails as many connection attempts as failures with err and
+// lets the later ones succeed.
+type flakyConnector struct {
+	failures int
+	err      error
+	attempts int
+}
+
+func (c *flakyConnector) Connect(context.Context) (driver.Conn, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		return nil, c.err
+	}
+	return conn{}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver { return nil }
+
+// conn is a connection that is only ever pinged.
+type conn struct{}
+
+func (conn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (conn) Close() error                        { return nil }
+func (conn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+func TestPingWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		timeout  time.Duration
+		attempts int
+		wantErr  string
+	}{
+		{"reachable", 0, nil, time.Second, 1, ""},
+		{"starting", 2, errRefused, 5 * time.Second, 3, ""},
+		{"wrong password", 5, &pq.Error{Code: "28P01", Message: "password authentication failed"}, 5 * time.Second, 1, "connecting to the database"},
+		{"down", 100, errRefused, 600 * time.Millisecond, 0, "database not reachable after"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var logged bytes.Buffer
+			log.SetOutput(&logged)
+			defer log.SetOutput(os.Stderr)
+
+			connector := &flakyConnector{failures: test.failures, err: test.err}
+			db := sql.OpenDB(connector)
+			defer db.Close()
+
+			err := pingWithRetry(db, test.timeout)
+			if test.wantErr == "" && err != nil {
+				t.Fatalf("pingWithRetry: %v", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("pingWithRetry error = %v, want one containing %q", err, test.wantErr)
+			}
+			if test.attempts > 0 && connector.attempts != test.attempts {
+				t.Errorf("pingWithRetry made %d attempts, want %d", connector.attempts, test.attempts)
+			}
+			// every announced retry has to be made
+			if retries := strings.Count(logged.String(), "retrying in"); retries != connector.attempts-1 {
+				t.Errorf("pingWithRetry announced %d retries and made %d attempts:\n%s", retries, connector.attempts, &logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"assignment2/auth"
+	"assignment2/problem"
+	"assignment2/store"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type AuthHandler struct {
+	users  store.UserStore
+	tokens *auth.Tokens
+}
+
+func NewAuthHandler(users store.UserStore, tokens *auth.Tokens) *AuthHandler {
+	return &AuthHandler{users: users, tokens: tokens}
+}
+
+// RegisterRequest creates an account. The "password" tag enforces
+// auth.MinPasswordLength and auth.MaxPasswordLength.
+type RegisterRequest struct {
+	Username string `json:"username" validate:"required,notblank,max=255"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+// normalizeUsername is applied to usernames at registration and login alike
+// so that both look up the same user.
+func normalizeUsername(username string) string {
+	return strings.TrimSpace(username)
+}
+
+type UserResponse struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// POST /api/auth/register
+func (a *AuthHandler) RegisterHandle(w http.ResponseWriter, r *http.Request) {
+	var registerRequest RegisterRequest
+	if !decodeRequest(w, r, &registerRequest) {
+		return
+	}
+	registerRequest.Username = normalizeUsername(registerRequest.Username)
+
+	passwordHash, err := auth.HashPassword(registerRequest.Password)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while hashing password", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	user := &store.User{Username: registerRequest.Username, Email: registerRequest.Email, PasswordHash: passwordHash}
+	userID, err := a.users.CreateUser(r.Context(), user)
+	if errors.Is(err, store.ErrUserExists) {
+		problem.Write(w, errUserExists)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while creating user", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	created, err := a.users.GetUser(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while getting created user", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newUserResponse(created))
+}
+
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    int64     `json:"user_id"`
+}
+
+// POST /api/auth/login
+func (a *AuthHandler) LoginHandle(w http.ResponseWriter, r *http.Request) {
+	var loginRequest LoginRequest
+	if !decodeRequest(w, r, &loginRequest) {
+		return
+	}
+
+	user, err := a.users.GetUserByUsername(r.Context(), normalizeUsername(loginRequest.Username))
+	if err != nil && !errors.Is(err, store.ErrUserNotFound) {
+		slog.ErrorContext(r.Context(), "Error while getting user", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	var passwordHash string
+	if user != nil {
+		passwordHash = user.PasswordHash
+	}
+	if err := auth.CheckPassword(passwordHash, loginRequest.Password); err != nil {
+		problem.Write(w, errInvalidCredentials.With(err.Error()))
+		return
+	}
+
+	token, expiresAt, err := a.tokens.Issue(auth.User{UserID: user.UserID, Username: user.Username})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while issuing token", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&LoginResponse{Token: token, TokenType: "Bearer", ExpiresAt: expiresAt, UserID: user.UserID})
+}
+
+// GET /api/auth/me
+func (a *AuthHandler) MeHandle(w http.ResponseWriter, r *http.Request) {
+	current, _ := auth.UserFromContext(r.Context())
+
+	user, err := a.users.GetUser(r.Context(), current.UserID)
+	if errors.Is(err, store.ErrUserNotFound) {
+		problem.Write(w, errUserNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while getting user", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newUserResponse(user))
+}
+
+func newUserResponse(user *store.User) UserResponse {
+	return UserResponse{UserID: user.UserID, Username: user.Username, Email: user.Email, CreatedAt: user.CreatedAt}
+}
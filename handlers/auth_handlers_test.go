@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRegisterPasswordLength(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		password string
+		status   int
+	}{
+		{"too short", "short", http.StatusUnprocessableEntity},
+		{"shortest", "12345678", http.StatusCreated},
+		// eight characters but sixteen bytes
+		{"multibyte", "ääääääää", http.StatusCreated},
+		{"longest", strings.Repeat("a", 72), http.StatusCreated},
+		{"too long", strings.Repeat("a", 73), http.StatusUnprocessableEntity},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			username := "user" + string(rune('a'+i))
+			rec := s.do(t, "", "POST", "/api/auth/register", map[string]any{
+				"username": username,
+				"email":    username + "@example.com",
+				"password": test.password,
+			})
+			if test.status != http.StatusCreated {
+				p := expectProblem(t, rec, test.status, "validation_failed")
+				if len(p.Errors) != 1 || p.Errors[0].Field != "password" {
+					t.Errorf("field errors = %+v, want one for password", p.Errors)
+				}
+				return
+			}
+			decode(t, rec, test.status, nil)
+		})
+	}
+}
+
+func TestLoginTrimsUsername(t *testing.T) {
+	s := newTestServer(t)
+
+	var user UserResponse
+	decode(t, s.do(t, "", "POST", "/api/auth/register", map[string]any{
+		"username": " carol ",
+		"email":    "carol@example.com",
+		"password": "correct horse",
+	}), http.StatusCreated, &user)
+	if user.Username != "carol" {
+		t.Fatalf("registered username = %q, want %q", user.Username, "carol")
+	}
+
+	for _, username := range []string{"carol", " carol", "carol\t"} {
+		var login LoginResponse
+		decode(t, s.do(t, "", "POST", "/api/auth/login", map[string]any{"username": username, "password": "correct horse"}), http.StatusOK, &login)
+		if login.UserID != user.UserID || login.Token == "" {
+			t.Errorf("login as %q = %+v", username, login)
+		}
+	}
+
+	expectProblem(t, s.do(t, "", "POST", "/api/auth/login", map[string]any{"username": "carol", "password": "wrong horse"}), http.StatusUnauthorized, "invalid_credentials")
+}
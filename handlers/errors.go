@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"assignment2/problem"
+	"assignment2/store"
+	"log/slog"
+	"net/http"
+)
+
+// The problems returned by the handlers. Those with a fixed detail are used
+// as they are; the others get their detail through With.
+var (
+	errEmptyBody        = problem.New(http.StatusBadRequest, "empty_body", "Request body must not be empty")
+	errInvalidJSON      = problem.New(http.StatusBadRequest, "invalid_json", "")
+	errInvalidParameter = problem.New(http.StatusBadRequest, "invalid_parameter", "")
+	errValidationFailed = problem.New(http.StatusUnprocessableEntity, "validation_failed", "Request body is invalid")
+	errNothingToUpdate  = problem.New(http.StatusBadRequest, "nothing_to_update", "Nothing to update, expected name or date_time")
+
+	errUserExists         = problem.New(http.StatusConflict, "user_exists", "Username or email already taken")
+	errUserNotFound       = problem.New(http.StatusNotFound, "user_not_found", "User not found")
+	errInvalidCredentials = problem.New(http.StatusUnauthorized, "invalid_credentials", "")
+
+	errMealNotFound            = problem.New(http.StatusNotFound, "meal_not_found", "Meal not found")
+	errMealModified            = problem.New(http.StatusConflict, "meal_modified", "Meal was modified by another request")
+	errMealIngredientExists    = problem.New(http.StatusConflict, "meal_ingredient_exists", "Ingredient is already part of this meal, use PUT /api/meals/{id}/ingredients/{ingredient_id} to change its amount")
+	errMealIngredientNotFound  = problem.New(http.StatusNotFound, "meal_ingredient_not_found", "Ingredient is not part of this meal")
+	errDuplicateMealIngredient = problem.New(http.StatusConflict, "duplicate_meal_ingredient", "Each ingredient may only be listed once per meal")
+
+	errIngredientNotFound = problem.New(http.StatusNotFound, "ingredient_not_found", "Ingredient not found")
+	// errUnknownIngredient is an ingredient named in a request body.
+	errUnknownIngredient  = problem.New(http.StatusUnprocessableEntity, "unknown_ingredient", "Ingredient not found")
+	errIngredientExists   = problem.New(http.StatusConflict, "ingredient_exists", "Ingredient already exists")
+	errBarcodeExists      = problem.New(http.StatusConflict, "barcode_exists", "Barcode is already used by another ingredient")
+	errIngredientIsRecipe = problem.New(http.StatusConflict, "ingredient_is_recipe", "Ingredient is a recipe, update it through /api/recipes")
+	errIngredientInUse    = problem.New(http.StatusConflict, "ingredient_in_use", "Ingredient is used by a recipe")
+
+	errUnknownNutrient   = problem.New(http.StatusUnprocessableEntity, "unknown_nutrient", "Nutrient is not in the catalog")
+	errDuplicateNutrient = problem.New(http.StatusBadRequest, "duplicate_nutrient", "")
+	errNutrientNotFound  = problem.New(http.StatusNotFound, "nutrient_not_found", "Nutrient is not in the catalog")
+	errGoalNotFound      = problem.New(http.StatusNotFound, "goal_not_found", "Goal not found")
+	errInvalidGoal       = problem.New(http.StatusBadRequest, "invalid_goal", "")
+
+	errRecipeNotFound            = problem.New(http.StatusNotFound, "recipe_not_found", "Recipe not found")
+	errNestedRecipe              = problem.New(http.StatusUnprocessableEntity, "nested_recipe", "Recipes cannot contain other recipes")
+	errDuplicateRecipeIngredient = problem.New(http.StatusBadRequest, "duplicate_recipe_ingredient", "Each ingredient may only be listed once per recipe")
+
+	errImportsDisabled    = problem.New(http.StatusNotFound, "imports_disabled", "Imports are disabled, IMPORT_DIR is not set")
+	errImportFileNotFound = problem.New(http.StatusNotFound, "import_file_not_found", "Import file not found")
+	errUnknownSource      = problem.New(http.StatusBadRequest, "unknown_source", "source must be fdc or off")
+	errImportFailed       = problem.New(http.StatusUnprocessableEntity, "import_failed", "")
+
+	errAlreadyExists     = problem.New(http.StatusConflict, "already_exists", "A record with the same key already exists")
+	errStillReferenced   = problem.New(http.StatusConflict, "still_referenced", "The record is still referenced by other records")
+	errReferenceNotFound = problem.New(http.StatusUnprocessableEntity, "reference_not_found", "A referenced record does not exist")
+	errConstraint        = problem.New(http.StatusUnprocessableEntity, "constraint_violation", "The request violates a data constraint")
+	errOutOfRange        = problem.New(http.StatusUnprocessableEntity, "value_out_of_range", "A value derived from the request is too large to be stored")
+	errInternal          = problem.New(http.StatusInternalServerError, "internal_error", "An unexpected error occurred")
+	errUnavailable       = problem.New(http.StatusServiceUnavailable, "database_unavailable", "The database is temporarily unavailable, try again later")
+)
+
+// retryAfter is the Retry-After, in seconds, sent with errUnavailable.
+const retryAfter = "5"
+
+// writeServerError answers an error the handler has no specific problem for.
+// Database constraint violations, which the store's own checks can miss when
+// requests race, and derived values too large for their column become 409 or
+// 422, and a database that cannot be reached a 503 with Retry-After; anything
+// else is a 500 whose details stay in the log.
+func writeServerError(w http.ResponseWriter, r *http.Request, err error) {
+	if store.Unavailable(err) {
+		w.Header().Set("Retry-After", retryAfter)
+		problem.Write(w, errUnavailable)
+		return
+	}
+	if store.OutOfRange(err) {
+		slog.WarnContext(r.Context(), "Value out of range", "error", err)
+		problem.Write(w, errOutOfRange)
+		return
+	}
+	violation, ok := store.ConstraintViolation(err)
+	if !ok {
+		problem.Write(w, errInternal)
+		return
+	}
+	slog.WarnContext(r.Context(), "Constraint violated", "constraint", violation.Constraint, "error", err)
+	switch violation.Kind {
+	case store.UniqueViolation, store.PrimaryKeyViolation:
+		problem.Write(w, errAlreadyExists)
+	case store.ReferencedViolation:
+		problem.Write(w, errStillReferenced)
+	case store.ForeignKeyViolation:
+		problem.Write(w, errReferenceNotFound)
+	default:
+		problem.Write(w, errConstraint)
+	}
+}
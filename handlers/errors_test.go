@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestWriteServerError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"unique violation", &pq.Error{Code: "23505", Constraint: "ingredients_name_key"}, http.StatusConflict, "already_exists"},
+		{"still referenced", &pq.Error{Code: "23503", Detail: `Key (ingredientid)=(1) is still referenced from table "recipe_ingredients".`}, http.StatusConflict, "still_referenced"},
+		{"missing reference", &pq.Error{Code: "23503", Detail: `Key (ingredientid)=(1) is not present in table "ingredients".`}, http.StatusUnprocessableEntity, "reference_not_found"},
+		{"check violation", &pq.Error{Code: "23514"}, http.StatusUnprocessableEntity, "constraint_violation"},
+		{"numeric overflow", &pq.Error{Code: "22003", Message: "numeric field overflow"}, http.StatusUnprocessableEntity, "value_out_of_range"},
+		{"anything else", &pq.Error{Code: "42P01", Message: `relation "meals" does not exist`}, http.StatusInternalServerError, "internal_error"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeServerError(rec, httptest.NewRequest("GET", "/", nil), test.err)
+			p := expectProblem(t, rec, test.status, test.code)
+			if p.Detail != "" && test.err != nil && p.Detail == test.err.Error() {
+				t.Errorf("problem detail leaks the database error %q", p.Detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"assignment2/problem"
+	"assignment2/store"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exportWriteTimeout replaces the server's WriteTimeout for exports, which
+// stream for as long as the range takes to read.
+const exportWriteTimeout = 10 * time.Minute
+
+// lastDate is used as the upper bound when an export has no "to" date.
+var lastDate = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+type ExportHandler struct {
+	meals store.MealStore
+}
+
+func NewExportHandler(meals store.MealStore) *ExportHandler {
+	return &ExportHandler{meals: meals}
+}
+
+// ExportMealResponse is a meal as returned by GET /api/meals/{id} together
+// with its nutrition as returned by GET /api/meals/{id}/nutrition.
+type ExportMealResponse struct {
+	*GetMealResponse
+	Nutrition *MealNutritionResponse `json:"nutrition"`
+}
+
+// exportEncoder writes one export format. begin is called before the first
+// meal and end after the last one; nothing is written before begin, so a
+// failing store query can still be answered with an error status.
+type exportEncoder interface {
+	contentType() string
+	begin(w io.Writer) error
+	meal(w io.Writer, export *store.MealExport) error
+	end(w io.Writer) error
+}
+
+var exportFormats = map[string]func(from string, to string) exportEncoder{
+	"json":   func(from string, to string) exportEncoder { return &jsonExport{from: from, to: to} },
+	"ndjson": func(from string, to string) exportEncoder { return &ndjsonExport{} },
+	"csv":    func(from string, to string) exportEncoder { return &csvExport{} },
+}
+
+// GET /api/export?from=YYYY-MM-DD&to=YYYY-MM-DD&format=json|ndjson|csv
+//
+// Both dates are optional and inclusive; the format defaults to json.
+func (h *ExportHandler) ExportHandle(w http.ResponseWriter, r *http.Request) {
+	from, err := queryDate(r, "from")
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	to, err := queryDate(r, "to")
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		problem.Write(w, errInvalidParameter.With("to must not be before from"))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	newEncoder, ok := exportFormats[format]
+	if !ok {
+		problem.Write(w, errInvalidParameter.With("format must be one of json, ndjson or csv"))
+		return
+	}
+	encoder := newEncoder(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if to.IsZero() {
+		to = lastDate
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	started := false
+	err = h.meals.ExportMeals(r.Context(), currentUserID(r), from, to, func(export *store.MealExport) error {
+		if !started {
+			started = true
+			if err := startExport(w, encoder, format); err != nil {
+				return err
+			}
+		}
+		return encoder.meal(w, export)
+	})
+	if err == nil && !started {
+		started = true
+		err = startExport(w, encoder, format)
+	}
+	if err == nil {
+		err = encoder.end(w)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while exporting meals", "error", err)
+		if !started {
+			writeServerError(w, r, err)
+		}
+		// otherwise the truncated body is all the client gets
+		return
+	}
+}
+
+func startExport(w http.ResponseWriter, encoder exportEncoder, format string) error {
+	w.Header().Set("Content-Type", encoder.contentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"meals.%s\"", format))
+	w.WriteHeader(http.StatusOK)
+	return encoder.begin(w)
+}
+
+func newExportMealResponse(export *store.MealExport) *ExportMealResponse {
+	return &ExportMealResponse{
+		GetMealResponse: newMealResponse(&export.Meal),
+		Nutrition:       newMealNutritionResponse(&export.Nutrition),
+	}
+}
+
+// jsonExport writes a single document, {"from":…,"to":…,"meals":[…]}, one
+// meal at a time.
+type jsonExport struct {
+	from, to string
+	count    int
+}
+
+func (e *jsonExport) contentType() string {
+	return "application/json"
+}
+
+func (e *jsonExport) begin(w io.Writer) error {
+	from, _ := json.Marshal(e.from)
+	to, _ := json.Marshal(e.to)
+	_, err := fmt.Fprintf(w, `{"from":%s,"to":%s,"meals":[`, from, to)
+	return err
+}
+
+func (e *jsonExport) meal(w io.Writer, export *store.MealExport) error {
+	if e.count > 0 {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	body, err := json.Marshal(newExportMealResponse(export))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func (e *jsonExport) end(w io.Writer) error {
+	_, err := io.WriteString(w, "]}\n")
+	return err
+}
+
+// ndjsonExport writes one meal per line.
+type ndjsonExport struct{}
+
+func (e *ndjsonExport) contentType() string {
+	return "application/x-ndjson"
+}
+
+func (e *ndjsonExport) begin(w io.Writer) error {
+	return nil
+}
+
+func (e *ndjsonExport) meal(w io.Writer, export *store.MealExport) error {
+	return json.NewEncoder(w).Encode(newExportMealResponse(export))
+}
+
+func (e *ndjsonExport) end(w io.Writer) error {
+	return nil
+}
+
+// csvExport writes one row per ingredient line and nutrient, labelled
+// "ingredient", followed by one "total" row per nutrient of the meal. A meal
+// without any nutrients gets a single "meal" row.
+type csvExport struct {
+	writer *csv.Writer
+}
+
+var csvExportHeader = []string{"type", "meal_id", "meal_name", "date", "time", "ingredient_id", "ingredient_name", "amount_in_grams", "nutrient", "unit", "amount"}
+
+func (e *csvExport) contentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (e *csvExport) begin(w io.Writer) error {
+	e.writer = csv.NewWriter(w)
+	return e.writer.Write(csvExportHeader)
+}
+
+func (e *csvExport) meal(w io.Writer, export *store.MealExport) error {
+	meal := []string{
+		strconv.FormatInt(export.Meal.MealID, 10),
+		export.Meal.Name,
+		export.Meal.Date.Format(time.DateOnly),
+		export.Meal.Time.Format(time.TimeOnly),
+	}
+	row := func(kind string, ingredient []string, nutrient *store.NutrientTotal) []string {
+		record := append([]string{kind}, meal...)
+		record = append(record, ingredient...)
+		if nutrient == nil {
+			return append(record, "", "", "")
+		}
+		return append(record, nutrient.Name, nutrient.Unit, formatAmount(roundAmount(nutrient.Amount)))
+	}
+
+	if len(export.Nutrition.Ingredients) == 0 && len(export.Nutrition.Totals) == 0 {
+		if err := e.writer.Write(row("meal", []string{"", "", ""}, nil)); err != nil {
+			return err
+		}
+	}
+	for _, line := range export.Nutrition.Ingredients {
+		ingredient := []string{strconv.FormatInt(line.IngredientID, 10), line.Name, formatAmount(line.AmountInGrams)}
+		if len(line.Nutrients) == 0 {
+			if err := e.writer.Write(row("ingredient", ingredient, nil)); err != nil {
+				return err
+			}
+		}
+		for i := range line.Nutrients {
+			if err := e.writer.Write(row("ingredient", ingredient, &line.Nutrients[i])); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range export.Nutrition.Totals {
+		if err := e.writer.Write(row("total", []string{"", "", ""}, &export.Nutrition.Totals[i])); err != nil {
+			return err
+		}
+	}
+	// pass each meal on instead of collecting rows in the csv buffer
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExport) end(w io.Writer) error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newExportServer returns a test server where alice has two meals on
+// 2023-11-20 and 2023-11-21 and one outside that range, and bob has one on
+// 2023-11-20.
+func newExportServer(t *testing.T) *testServer {
+	t.Helper()
+	s := newTestServer(t)
+	oats := s.createIngredientWith(t, "Oats", map[string]float64{"Protein": 10, "Fat": 5})
+	milk := s.createIngredient(t, s.alice, "Milk")
+
+	s.createMeal(t, s.alice, map[string]any{
+		"name":      "Breakfast",
+		"date_time": "2023-11-20T08:30:00Z",
+		"ingredients": []map[string]any{
+			{"ingredient_id": oats, "amount_in_grams": 50},
+			{"ingredient_id": milk, "amount_in_grams": 200},
+		},
+	})
+	s.createMeal(t, s.alice, map[string]any{"name": "Snack", "date_time": "2023-11-21T16:00:00Z"})
+	s.createMeal(t, s.alice, map[string]any{"name": "Later", "date_time": "2023-11-25T12:00:00Z"})
+	s.createMeal(t, s.bob, map[string]any{
+		"name":        "Breakfast of another user",
+		"date_time":   "2023-11-20T08:30:00Z",
+		"ingredients": []map[string]any{{"ingredient_id": oats, "amount_in_grams": 100}},
+	})
+	return s
+}
+
+func TestExportJSON(t *testing.T) {
+	s := newExportServer(t)
+
+	rec := s.do(t, s.alice, "GET", "/api/export?from=2023-11-20&to=2023-11-21", nil)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var export struct {
+		From  string               `json:"from"`
+		To    string               `json:"to"`
+		Meals []ExportMealResponse `json:"meals"`
+	}
+	decode(t, rec, http.StatusOK, &export)
+	if export.From != "2023-11-20" || export.To != "2023-11-21" || len(export.Meals) != 2 {
+		t.Fatalf("export = %+v", export)
+	}
+	breakfast := export.Meals[0]
+	if breakfast.Name != "Breakfast" || len(breakfast.Ingredients) != 2 || len(breakfast.Nutrition.Totals) != 2 {
+		t.Errorf("first meal = %+v with nutrition %+v", breakfast.GetMealResponse, breakfast.Nutrition)
+	}
+	if export.Meals[1].Name != "Snack" || len(export.Meals[1].Nutrition.Totals) != 0 {
+		t.Errorf("second meal = %+v", export.Meals[1].GetMealResponse)
+	}
+
+	// an empty range is still a complete document
+	rec = s.do(t, s.alice, "GET", "/api/export?from=2024-01-01", nil)
+	decode(t, rec, http.StatusOK, nil)
+	if got := rec.Body.String(); got != `{"from":"2024-01-01","to":"","meals":[]}`+"\n" {
+		t.Errorf("empty export = %q", got)
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	s := newExportServer(t)
+
+	rec := s.do(t, s.alice, "GET", "/api/export?format=ndjson&from=2023-11-20", nil)
+	decode(t, rec, http.StatusOK, nil)
+	if got := rec.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var names []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var meal ExportMealResponse
+		if err := json.Unmarshal(scanner.Bytes(), &meal); err != nil {
+			t.Fatalf("decoding line %q: %v", scanner.Text(), err)
+		}
+		names = append(names, meal.Name)
+	}
+	if want := []string{"Breakfast", "Snack", "Later"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("exported meals = %q, want %q", names, want)
+	}
+
+	rec = s.do(t, s.alice, "GET", "/api/export?format=ndjson&to=2000-01-01", nil)
+	decode(t, rec, http.StatusOK, nil)
+	if rec.Body.Len() != 0 {
+		t.Errorf("empty export = %q, want no lines", rec.Body)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	s := newExportServer(t)
+
+	rec := s.do(t, s.alice, "GET", "/api/export?format=csv&from=2023-11-20&to=2023-11-21", nil)
+	decode(t, rec, http.StatusOK, nil)
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="meals.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	breakfast := []string{"1", "Breakfast", "2023-11-20", "08:30:00"}
+	row := func(kind string, meal []string, rest ...string) []string {
+		return append(append([]string{kind}, meal...), rest...)
+	}
+	want := [][]string{
+		csvExportHeader,
+		row("ingredient", breakfast, "1", "Oats", "50", "Fat", "g", "2.5"),
+		row("ingredient", breakfast, "1", "Oats", "50", "Protein", "g", "5"),
+		// an ingredient without nutrients still gets its row
+		row("ingredient", breakfast, "2", "Milk", "200", "", "", ""),
+		row("total", breakfast, "", "", "", "Fat", "g", "2.5"),
+		row("total", breakfast, "", "", "", "Protein", "g", "5"),
+		// and so does a meal without ingredients
+		row("meal", []string{"2", "Snack", "2023-11-21", "16:00:00"}, "", "", "", "", "", ""),
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("CSV rows =\n%q\nwant\n%q", rows, want)
+	}
+
+	rec = s.do(t, s.alice, "GET", "/api/export?format=csv&from=2024-01-01", nil)
+	decode(t, rec, http.StatusOK, nil)
+	if got := rec.Body.String(); got != strings.Join(csvExportHeader, ",")+"\n" {
+		t.Errorf("empty export = %q, want only the header", got)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, query := range []string{
+		"format=xml",
+		"from=20-11-2023",
+		"to=2023-11-31",
+		"from=2023-11-21&to=2023-11-20",
+	} {
+		t.Run(query, func(t *testing.T) {
+			expectProblem(t, s.do(t, s.alice, "GET", "/api/export?"+query, nil), http.StatusBadRequest, "invalid_parameter")
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"assignment2/catalog"
+	"assignment2/problem"
+	"assignment2/reports"
+	"assignment2/store"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type GoalHandler struct {
+	meals store.MealStore
+	goals store.GoalStore
+}
+
+func NewGoalHandler(meals store.MealStore, goals store.GoalStore) *GoalHandler {
+	return &GoalHandler{meals: meals, goals: goals}
+}
+
+type GoalResponse struct {
+	NutrientID int64     `json:"nutrient_id"`
+	Name       string    `json:"name"`
+	Unit       string    `json:"unit"`
+	Min        *float64  `json:"min"`
+	Max        *float64  `json:"max"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func newGoalResponse(goal *store.Goal) GoalResponse {
+	return GoalResponse{NutrientID: goal.NutrientID, Name: goal.Name, Unit: goal.Unit, Min: goal.Min, Max: goal.Max, UpdatedAt: goal.UpdatedAt}
+}
+
+// GET /api/goals
+func (g *GoalHandler) ListGoalsHandle(w http.ResponseWriter, r *http.Request) {
+	goals, err := g.goals.ListGoals(r.Context(), currentUserID(r))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while listing goals", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	response := []GoalResponse{}
+	for i := range goals {
+		response = append(response, newGoalResponse(&goals[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&response)
+}
+
+// SetGoalRequest sets a daily range for a nutrient. Either bound may be left
+// out to only set a minimum or a maximum.
+type SetGoalRequest struct {
+	Min *float64 `json:"min" validate:"omitnil,gte=0,lte=99999999.99"`
+	Max *float64 `json:"max" validate:"omitnil,gte=0,lte=99999999.99"`
+}
+
+// validate checks how min and max relate; each of them has been checked on
+// its own by decodeRequest.
+func (req *SetGoalRequest) validate() error {
+	if req.Min == nil && req.Max == nil {
+		return errors.New("a goal needs a min, a max or both")
+	}
+	if req.Min != nil && req.Max != nil && *req.Min > *req.Max {
+		return errors.New("min must not be greater than max")
+	}
+	if (req.Min == nil || *req.Min == 0) && (req.Max == nil || *req.Max == 0) {
+		return errors.New("min or max must be greater than zero")
+	}
+	return nil
+}
+
+// goalNutrient resolves the {nutrient} path variable, which may be any name
+// or alias known to the catalog.
+func goalNutrient(r *http.Request) (string, bool) {
+	nutrient, ok := catalog.Lookup(mux.Vars(r)["nutrient"])
+	return nutrient.Name, ok
+}
+
+// PUT /api/goals/{nutrient}
+func (g *GoalHandler) SetGoalHandle(w http.ResponseWriter, r *http.Request) {
+	name, ok := goalNutrient(r)
+	if !ok {
+		problem.Write(w, errNutrientNotFound)
+		return
+	}
+
+	var goalRequest SetGoalRequest
+	if !decodeRequest(w, r, &goalRequest) {
+		return
+	}
+	if err := goalRequest.validate(); err != nil {
+		problem.Write(w, errInvalidGoal.With(err.Error()))
+		return
+	}
+
+	goal, created, err := g.goals.SetGoal(r.Context(), currentUserID(r), store.Goal{Name: name, Min: goalRequest.Min, Max: goalRequest.Max})
+	if errors.Is(err, store.ErrNutrientNotFound) {
+		problem.Write(w, errNutrientNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while setting goal", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newGoalResponse(goal))
+}
+
+// DELETE /api/goals/{nutrient}
+func (g *GoalHandler) DeleteGoalHandle(w http.ResponseWriter, r *http.Request) {
+	name, ok := goalNutrient(r)
+	if !ok {
+		problem.Write(w, errGoalNotFound)
+		return
+	}
+
+	err := g.goals.DeleteGoal(r.Context(), currentUserID(r), name)
+	if errors.Is(err, store.ErrGoalNotFound) {
+		problem.Write(w, errGoalNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while deleting goal", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type GoalProgressResponse struct {
+	NutrientID int64    `json:"nutrient_id"`
+	Name       string   `json:"name"`
+	Unit       string   `json:"unit"`
+	Min        *float64 `json:"min"`
+	Max        *float64 `json:"max"`
+	Intake     float64  `json:"intake"`
+	Percent    float64  `json:"percent"`
+	Status     string   `json:"status"`
+	Under      bool     `json:"under"`
+	Over       bool     `json:"over"`
+}
+
+type ProgressResponse struct {
+	Date  string                 `json:"date"`
+	Goals []GoalProgressResponse `json:"goals"`
+}
+
+// GET /api/goals/progress?date=YYYY-MM-DD, defaulting to today (UTC)
+func (g *GoalHandler) GoalProgressHandle(w http.ResponseWriter, r *http.Request) {
+	date, err := queryDate(r, "date")
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+
+	progress, err := reports.DailyProgress(r.Context(), g.meals, g.goals, currentUserID(r), date)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while computing goal progress", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	response := ProgressResponse{Date: progress.Date.Format(time.DateOnly), Goals: []GoalProgressResponse{}}
+	for _, goal := range progress.Goals {
+		response.Goals = append(response.Goals, GoalProgressResponse{
+			NutrientID: goal.Goal.NutrientID,
+			Name:       goal.Goal.Name,
+			Unit:       goal.Goal.Unit,
+			Min:        goal.Goal.Min,
+			Max:        goal.Goal.Max,
+			Intake:     roundAmount(goal.Intake),
+			Percent:    roundAmount(goal.Percent),
+			Status:     goal.Status,
+			Under:      goal.Status == reports.StatusUnder,
+			Over:       goal.Status == reports.StatusOver,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&response)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGoalCRUD(t *testing.T) {
+	s := newTestServer(t)
+
+	// an alias names the catalog nutrient, bounds are rounded like the column
+	var goal GoalResponse
+	decode(t, s.do(t, s.alice, "PUT", "/api/goals/proteins", map[string]any{"min": 50.004}), http.StatusCreated, &goal)
+	if goal.Name != "Protein" || goal.Min == nil || *goal.Min != 50 || goal.Max != nil {
+		t.Fatalf("PUT goal = %+v", goal)
+	}
+	decode(t, s.do(t, s.alice, "PUT", "/api/goals/Protein", map[string]any{"min": 50, "max": 80.126}), http.StatusOK, &goal)
+
+	var goals []GoalResponse
+	decode(t, s.do(t, s.alice, "GET", "/api/goals", nil), http.StatusOK, &goals)
+	if len(goals) != 1 || *goals[0].Min != *goal.Min || goals[0].Max == nil || *goals[0].Max != 80.13 || *goal.Max != *goals[0].Max {
+		t.Errorf("GET goals = %+v after PUT returned %+v", goals, goal)
+	}
+
+	decode(t, s.do(t, s.bob, "GET", "/api/goals", nil), http.StatusOK, &goals)
+	if len(goals) != 0 {
+		t.Errorf("GET goals of another user = %+v, want none", goals)
+	}
+	expectProblem(t, s.do(t, s.bob, "DELETE", "/api/goals/Protein", nil), http.StatusNotFound, "goal_not_found")
+
+	decode(t, s.do(t, s.alice, "DELETE", "/api/goals/Protein", nil), http.StatusNoContent, nil)
+	expectProblem(t, s.do(t, s.alice, "DELETE", "/api/goals/Protein", nil), http.StatusNotFound, "goal_not_found")
+}
+
+func TestGoalErrors(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"unknown nutrient", "/api/goals/Unobtainium", map[string]any{"min": 1}, http.StatusNotFound, "nutrient_not_found"},
+		{"no bounds", "/api/goals/Protein", map[string]any{}, http.StatusBadRequest, "invalid_goal"},
+		{"min above max", "/api/goals/Protein", map[string]any{"min": 10, "max": 5}, http.StatusBadRequest, "invalid_goal"},
+		{"only zeros", "/api/goals/Protein", map[string]any{"min": 0, "max": 0}, http.StatusBadRequest, "invalid_goal"},
+		{"negative bound", "/api/goals/Protein", map[string]any{"min": -1}, http.StatusUnprocessableEntity, "validation_failed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectProblem(t, s.do(t, s.alice, "PUT", test.path, test.body), test.status, test.code)
+		})
+	}
+	expectProblem(t, s.do(t, s.alice, "GET", "/api/goals/progress?date=20-11-2023", nil), http.StatusBadRequest, "invalid_parameter")
+}
+
+func TestGoalProgress(t *testing.T) {
+	s := newTestServer(t)
+
+	var created CreateIngredientResponse
+	decode(t, s.do(t, s.alice, "POST", "/api/ingredients", map[string]any{
+		"name": "Oats",
+		"nutrients": []map[string]any{
+			{"name": "Protein", "amount": 10},
+			{"name": "Fat", "amount": 5},
+			{"name": "Energy", "amount": 400},
+		},
+	}), http.StatusCreated, &created)
+	oats := created.IngredientID
+
+	// 200 g of oats on the day and meals that must not count
+	s.createMeal(t, s.alice, map[string]any{
+		"name":        "Breakfast",
+		"date_time":   "2023-11-20T08:30:00Z",
+		"ingredients": []map[string]any{{"ingredient_id": oats, "amount_in_grams": 200}},
+	})
+	s.createMeal(t, s.alice, map[string]any{
+		"name":        "Next breakfast",
+		"date_time":   "2023-11-21T08:30:00Z",
+		"ingredients": []map[string]any{{"ingredient_id": oats, "amount_in_grams": 500}},
+	})
+	s.createMeal(t, s.bob, map[string]any{
+		"name":        "Breakfast of another user",
+		"date_time":   "2023-11-20T08:30:00Z",
+		"ingredients": []map[string]any{{"ingredient_id": oats, "amount_in_grams": 500}},
+	})
+
+	for nutrient, body := range map[string]map[string]any{
+		"Protein":     {"min": 50},
+		"Fat":         {"max": 8},
+		"Energy":      {"min": 500, "max": 2000},
+		"Sodium":      {"max": 2300},
+		"Vitamin%20C": {"min": 3},
+	} {
+		decode(t, s.do(t, s.alice, "PUT", "/api/goals/"+nutrient, body), http.StatusCreated, nil)
+	}
+
+	var progress ProgressResponse
+	decode(t, s.do(t, s.alice, "GET", "/api/goals/progress?date=2023-11-20", nil), http.StatusOK, &progress)
+	if progress.Date != "2023-11-20" {
+		t.Errorf("progress date = %q, want 2023-11-20", progress.Date)
+	}
+
+	type result struct {
+		intake, percent float64
+		status          string
+		under, over     bool
+	}
+	want := map[string]result{
+		// the percent is relative to the minimum, or to the maximum without one
+		"Protein": {20, 40, "under", true, false},
+		"Fat":     {10, 125, "over", false, true},
+		"Energy":  {800, 160, "within", false, false},
+		// nutrients not eaten count as zero
+		"Sodium":    {0, 0, "within", false, false},
+		"Vitamin C": {0, 0, "under", true, false},
+	}
+	if len(progress.Goals) != len(want) {
+		t.Fatalf("progress has %d goals, want %d: %+v", len(progress.Goals), len(want), progress.Goals)
+	}
+	for _, goal := range progress.Goals {
+		got := result{goal.Intake, goal.Percent, goal.Status, goal.Under, goal.Over}
+		if got != want[goal.Name] {
+			t.Errorf("progress of %s = %+v, want %+v", goal.Name, got, want[goal.Name])
+		}
+	}
+}
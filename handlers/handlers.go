@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"assignment2/auth"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// pathID parses the named mux path variable as a database ID.
+func pathID(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return id, nil
+}
+
+// currentUserID returns the ID of the user authenticated by auth.Middleware.
+func currentUserID(r *http.Request) int64 {
+	user, _ := auth.UserFromContext(r.Context())
+	return user.UserID
+}
+
+// queryLimit parses the optional "limit" query parameter.
+func queryLimit(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	return limit, nil
+}
+
+// queryDate parses an optional YYYY-MM-DD query parameter, returning the zero
+// time when it is absent.
+func queryDate(r *http.Request, name string) (time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date formatted as YYYY-MM-DD", name)
+	}
+	return date, nil
+}
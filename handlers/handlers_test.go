@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"assignment2/auth"
+	"assignment2/catalog"
+	"assignment2/problem"
+	"assignment2/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// testServer serves the auth, meal, ingredient, recipe, goal and export
+// routes of main on an in-memory store with two registered users.
+type testServer struct {
+	store  *store.Memory
+	router *mux.Router
+	alice  string
+	bob    string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ctx := context.Background()
+	st := store.NewMemory()
+	if err := st.SyncNutrientCatalog(ctx, catalog.Nutrients); err != nil {
+		t.Fatalf("SyncNutrientCatalog: %v", err)
+	}
+	tokens := auth.NewTokens([]byte("test secret"), time.Hour)
+
+	r := mux.NewRouter()
+	authHandler := NewAuthHandler(st, tokens)
+	r.HandleFunc("/api/auth/register", authHandler.RegisterHandle).Methods("POST")
+	r.HandleFunc("/api/auth/login", authHandler.LoginHandle).Methods("POST")
+
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(auth.Middleware(tokens))
+
+	mealHandler := NewMealHandler(st)
+	api.HandleFunc("/meals", mealHandler.ListMealsHandle).Methods("GET")
+	api.HandleFunc("/meals", mealHandler.CreateMealHandle).Methods("POST")
+	api.HandleFunc("/meals/{id}", mealHandler.GetMealHandle).Methods("GET")
+	api.HandleFunc("/meals/{id}/nutrition", mealHandler.GetMealNutritionHandle).Methods("GET")
+	api.HandleFunc("/meals/{id}/ingredients", mealHandler.AddIngredientToMealHandle).Methods("POST")
+	api.HandleFunc("/meals/{id}/ingredients/{ingredient_id}", mealHandler.UpdateIngredientInMealHandle).Methods("PUT")
+	api.HandleFunc("/meals/{id}/ingredients/{ingredient_id}", mealHandler.RemoveIngredientFromMealHandle).Methods("DELETE")
+	api.HandleFunc("/meals/{id}", mealHandler.UpdateMealHandle).Methods("PATCH")
+	api.HandleFunc("/meals/{id}", mealHandler.DeleteMealHandle).Methods("DELETE")
+
+	ingredientHandler := NewIngredientHandler(st)
+	api.HandleFunc("/ingredients", ingredientHandler.ListIngredientsHandle).Methods("GET")
+	api.HandleFunc("/ingredients", ingredientHandler.CreateIngredientHandle).Methods("POST")
+	api.HandleFunc("/ingredients/by-barcode/{code}", ingredientHandler.GetIngredientByBarcodeHandle).Methods("GET")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.GetIngredientHandle).Methods("GET")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.UpdateIngredientHandle).Methods("PUT")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.PatchIngredientHandle).Methods("PATCH")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.DeleteIngredientHandle).Methods("DELETE")
+
+	recipeHandler := NewRecipeHandler(st)
+	api.HandleFunc("/recipes", recipeHandler.CreateRecipeHandle).Methods("POST")
+	api.HandleFunc("/recipes/{id}", recipeHandler.GetRecipeHandle).Methods("GET")
+	api.HandleFunc("/recipes/{id}", recipeHandler.UpdateRecipeHandle).Methods("PUT")
+	api.HandleFunc("/recipes/{id}", recipeHandler.DeleteRecipeHandle).Methods("DELETE")
+
+	goalHandler := NewGoalHandler(st, st)
+	api.HandleFunc("/goals", goalHandler.ListGoalsHandle).Methods("GET")
+	api.HandleFunc("/goals/progress", goalHandler.GoalProgressHandle).Methods("GET")
+	api.HandleFunc("/goals/{nutrient}", goalHandler.SetGoalHandle).Methods("PUT")
+	api.HandleFunc("/goals/{nutrient}", goalHandler.DeleteGoalHandle).Methods("DELETE")
+
+	exportHandler := NewExportHandler(st)
+	api.HandleFunc("/export", exportHandler.ExportHandle).Methods("GET")
+
+	s := &testServer{store: st, router: r}
+	s.alice = s.register(t, tokens, "alice")
+	s.bob = s.register(t, tokens, "bob")
+	return s
+}
+
+// register creates a user and returns a bearer token for it.
+func (s *testServer) register(t *testing.T, tokens *auth.Tokens, username string) string {
+	t.Helper()
+	userID, err := s.store.CreateUser(context.Background(), &store.User{Username: username, Email: username + "@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	token, _, err := tokens.Issue(auth.User{UserID: userID, Username: username})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
+
+// do sends a request as the user owning token.
+func (s *testServer) do(t *testing.T, token string, method string, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	return s.serve(s.request(t, token, method, path, body))
+}
+
+// request builds a request as the user owning token. A non-nil body is
+// encoded as JSON unless it already is a string.
+func (s *testServer) request(t *testing.T, token string, method string, path string, body any) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	switch body := body.(type) {
+	case nil:
+	case string:
+		buf.WriteString(body)
+	default:
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode decodes the response body into dst after checking the status.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, status int, dst any) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body)
+	}
+	if dst == nil {
+		return
+	}
+	if err := json.NewDecoder(rec.Body).Decode(dst); err != nil {
+		t.Fatalf("decoding response body: %v", err)
+	}
+}
+
+// expectProblem checks that the response is a problem with the given status
+// and code and returns it.
+func expectProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) problem.Problem {
+	t.Helper()
+	if got := rec.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Fatalf("Content-Type = %q, want %q; body: %s", got, problem.ContentType, rec.Body)
+	}
+	var p problem.Problem
+	decode(t, rec, status, &p)
+	if p.Code != code {
+		t.Fatalf("problem code = %q, want %q; problem: %+v", p.Code, code, p)
+	}
+	return p
+}
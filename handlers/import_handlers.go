@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"assignment2/importer"
+	"assignment2/problem"
+	"assignment2/store"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// importWriteTimeout replaces the server's write timeout for imports, which
+// can take minutes for the larger dumps.
+const importWriteTimeout = 10 * time.Minute
+
+type ImportHandler struct {
+	ingredients store.IngredientStore
+	dir         string
+}
+
+// NewImportHandler serves imports of dumps stored below dir. An empty dir
+// disables imports.
+func NewImportHandler(ingredients store.IngredientStore, dir string) *ImportHandler {
+	return &ImportHandler{ingredients: ingredients, dir: dir}
+}
+
+// ImportRequest names a dump relative to the server's import directory.
+type ImportRequest struct {
+	Source    string `json:"source" validate:"required,oneof=fdc off"`
+	Path      string `json:"path" validate:"required"`
+	BatchSize int    `json:"batch_size" validate:"gte=0"`
+}
+
+type ImportResponse struct {
+	Source    string `json:"source"`
+	Path      string `json:"path"`
+	Read      int    `json:"read"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Skipped   int    `json:"skipped"`
+}
+
+// POST /api/imports
+func (h *ImportHandler) CreateImportHandle(w http.ResponseWriter, r *http.Request) {
+	if h.dir == "" {
+		problem.Write(w, errImportsDisabled)
+		return
+	}
+
+	var importRequest ImportRequest
+	if !decodeRequest(w, r, &importRequest) {
+		return
+	}
+	if !filepath.IsLocal(importRequest.Path) {
+		writeValidationErrors(w, []problem.FieldError{{Field: "path", Message: "must be relative to the import directory"}})
+		return
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(importWriteTimeout))
+	summary, err := importer.Import(r.Context(), h.ingredients, importRequest.Source, filepath.Join(h.dir, importRequest.Path), importRequest.BatchSize)
+	if errors.Is(err, importer.ErrUnknownSource) {
+		problem.Write(w, errUnknownSource)
+		return
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		problem.Write(w, errImportFileNotFound)
+		return
+	}
+	if errors.Is(err, importer.ErrInvalidDump) {
+		// the detail names the dump relative to the import directory
+		detail := strings.ReplaceAll(err.Error(), h.dir+string(filepath.Separator), "")
+		problem.Write(w, errImportFailed.With(detail))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while importing ingredients", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&ImportResponse{
+		Source:    importRequest.Source,
+		Path:      importRequest.Path,
+		Read:      summary.Read,
+		Created:   summary.Created,
+		Updated:   summary.Updated,
+		Unchanged: summary.Unchanged,
+		Skipped:   summary.Skipped,
+	})
+}
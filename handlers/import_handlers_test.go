@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"assignment2/store"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingStore fails every upsert with err.
+type failingStore struct {
+	*store.Memory
+	err error
+}
+
+func (s failingStore) UpsertIngredients(context.Context, []store.Ingredient) (*store.UpsertSummary, error) {
+	return nil, s.err
+}
+
+func TestImportErrors(t *testing.T) {
+	s := newTestServer(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"products.jsonl":  `{"code": "4006381333931", "product_name": "Muesli", "nutriments": {"proteins_100g": 9.5}}` + "\n",
+		"malformed.jsonl": `{"code": "4006381333931", "product_name": `,
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		ingredients store.IngredientStore
+		path        string
+		status      int
+		code        string
+	}{
+		{"malformed dump", s.store, "malformed.jsonl", http.StatusUnprocessableEntity, "import_failed"},
+		{"missing dump", s.store, "missing.jsonl", http.StatusNotFound, "import_file_not_found"},
+		{"failing store", failingStore{s.store, errors.New(`pq: relation "ingredients" does not exist`)}, "products.jsonl", http.StatusInternalServerError, "internal_error"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewImportHandler(test.ingredients, dir)
+			rec := httptest.NewRecorder()
+			h.CreateImportHandle(rec, s.request(t, s.alice, "POST", "/api/imports", map[string]any{"source": "off", "path": test.path}))
+			p := expectProblem(t, rec, test.status, test.code)
+			if strings.Contains(p.Detail, dir) || strings.Contains(p.Detail, "pq:") {
+				t.Errorf("problem detail %q leaks server internals", p.Detail)
+			}
+		})
+	}
+}
@@ -1,33 +1,100 @@
 package handlers
 
 import (
-	"database/sql"
+	"assignment2/barcode"
+	"assignment2/catalog"
+	"assignment2/problem"
+	"assignment2/store"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 type IngredientHandler struct {
-	db *sql.DB
+	ingredients store.IngredientStore
 }
 
-func NewIngredientHandler(db *sql.DB) *IngredientHandler {
-	return &IngredientHandler{db: db}
+func NewIngredientHandler(ingredients store.IngredientStore) *IngredientHandler {
+	return &IngredientHandler{ingredients: ingredients}
 }
 
-func convertToPerHundredGrams(amount float64, servingSizeInGrams float64) float64 {
-	return (amount / servingSizeInGrams) * 100
+// NutrientAmount is a nutrient amount per serving as sent by clients.
+type NutrientAmount struct {
+	Name   string  `json:"name" validate:"required,notblank"`
+	Amount float64 `json:"amount" validate:"gte=0,lte=99999999.99"`
+}
+
+// errUnknownNutrients is returned by toNutrientValues when names are not in
+// the nutrient catalog.
+var errUnknownNutrients = errors.New("unknown nutrients")
+
+// toNutrientValues normalises validated per serving amounts to amounts per
+// 100 g. A zero serving size means the amounts already are per 100 g. Names
+// are resolved to their canonical catalog names. Amounts that grow past
+// maxAmount when scaled to 100 g are reported as a validation_failed problem.
+func toNutrientValues(nutrients []NutrientAmount, servingSizeInGrams float64) ([]store.NutrientValue, error) {
+	if servingSizeInGrams == 0 {
+		servingSizeInGrams = 100
+	}
+
+	seen := make(map[string]string)
+	var unknown []string
+	var tooLarge []problem.FieldError
+	var values []store.NutrientValue
+	for i, nutrient := range nutrients {
+		canonical, ok := catalog.Lookup(nutrient.Name)
+		if !ok {
+			unknown = append(unknown, strconv.Quote(nutrient.Name))
+			continue
+		}
+		if previous, ok := seen[canonical.Name]; ok {
+			return nil, fmt.Errorf("nutrients %q and %q are both %s", previous, nutrient.Name, canonical.Name)
+		}
+		seen[canonical.Name] = nutrient.Name
+
+		amount := catalog.ConvertToPerHundredGrams(nutrient.Amount, servingSizeInGrams)
+		if amount > maxAmount {
+			tooLarge = append(tooLarge, problem.FieldError{
+				Field:   fmt.Sprintf("nutrients[%d].amount", i),
+				Message: fmt.Sprintf("must not be greater than %.2f once scaled to 100 g", maxAmount),
+			})
+			continue
+		}
+		values = append(values, store.NutrientValue{Name: canonical.Name, AmountPer100g: amount})
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w %s, GET /api/nutrients lists the accepted names", errUnknownNutrients, strings.Join(unknown, ", "))
+	}
+	if len(tooLarge) > 0 {
+		return nil, validationProblem(tooLarge)
+	}
+	return values, nil
+}
+
+// nutrientsProblem is the problem for an error returned by toNutrientValues.
+func nutrientsProblem(err error) *problem.Problem {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	if errors.Is(err, errUnknownNutrients) {
+		return errUnknownNutrient.With(err.Error())
+	}
+	return errDuplicateNutrient.With(err.Error())
 }
 
 type CreateIngredientRequest struct {
-	Name               string  `json:"name" validate:"required"`
-	ServingSizeInGrams float64 `json:"serving_size_in_grams"`
-	Nutrients          []struct {
-		Name   string  `json:"name"`
-		Amount float64 `json:"amount"`
-	} `json:"nutrients" validate:"required"`
+	Name               string           `json:"name" validate:"required,notblank,max=255"`
+	Barcode            string           `json:"barcode" validate:"omitempty,gtin"`
+	Brand              string           `json:"brand" validate:"max=255"`
+	ServingSizeInGrams float64          `json:"serving_size_in_grams" validate:"gte=0,lte=99999999.99"`
+	Nutrients          []NutrientAmount `json:"nutrients" validate:"required,dive"`
 }
 
 type CreateIngredientResponse struct {
@@ -35,257 +102,303 @@ type CreateIngredientResponse struct {
 }
 
 func (i *IngredientHandler) CreateIngredientHandle(w http.ResponseWriter, r *http.Request) {
-	var ingredientRequest *CreateIngredientRequest
-	err := json.NewDecoder(r.Body).Decode(&ingredientRequest)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var ingredientRequest CreateIngredientRequest
+	if !decodeRequest(w, r, &ingredientRequest) {
 		return
 	}
 
-	// check if ingredient already exists
-	// if exists, return error
-	// if not exists, create ingredient
-	result, err := i.db.Query("SELECT * FROM Ingredients WHERE Name = $1", ingredientRequest.Name)
+	nutrients, err := toNutrientValues(ingredientRequest.Nutrients, ingredientRequest.ServingSizeInGrams)
 	if err != nil {
-		log.Println("Error while querying Ingredients table")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Write(w, nutrientsProblem(err))
 		return
 	}
+	ingredient := &store.Ingredient{
+		Name:      ingredientRequest.Name,
+		Barcode:   normalizeBarcode(ingredientRequest.Barcode),
+		Brand:     strings.TrimSpace(ingredientRequest.Brand),
+		Nutrients: nutrients,
+	}
 
-	if result.Next() {
-		log.Println("Ingredient already exists")
-		http.Error(w, "Ingredient already exists", http.StatusBadRequest)
+	ingredientID, err := i.ingredients.CreateIngredient(r.Context(), ingredient)
+	if errors.Is(err, store.ErrNutrientNotFound) {
+		problem.Write(w, errUnknownNutrient)
 		return
 	}
-	err = result.Close()
-	if err != nil {
-		log.Println("Error while closing result of SELECT * FROM Ingredients")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if errors.Is(err, store.ErrIngredientExists) {
+		problem.Write(w, errIngredientExists)
 		return
 	}
-
-	tx, err := i.db.BeginTx(r.Context(), nil)
-	if err != nil {
-		log.Println("Error while starting transaction")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if errors.Is(err, store.ErrBarcodeExists) {
+		problem.Write(w, errBarcodeExists)
 		return
 	}
-
-	// create ingredient
-	res, err := tx.QueryContext(r.Context(), "INSERT INTO Ingredients (Name) VALUES ($1) RETURNING IngredientID", ingredientRequest.Name)
-	if err != nil {
-		log.Println("Error while inserting ingredient into Ingredients table")
-		log.Println(err)
-		tx.Rollback()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var ingredientID int64
-	if res.Next() {
-		err = res.Scan(&ingredientID)
-		if err != nil {
-			log.Println("Error while scanning ingredientID")
-			log.Println(err)
-			tx.Rollback()
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	err = res.Close()
 	if err != nil {
-		log.Println("Error while closing result of INSERT INTO Ingredients")
-		log.Println(err)
-		tx.Rollback()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	log.Printf("Created ingredient with ID %d\n", ingredientID)
-
-	// create nutrients
-	for _, nutrient := range ingredientRequest.Nutrients {
-		// check if nutrient already exists
-		// if exists, use existing nutrient
-		// if not exists, create nutrient
-		log.Printf("Checking if nutrient %s already exists\n", nutrient.Name)
-
-		result, err = tx.QueryContext(r.Context(), "SELECT NutrientID FROM Nutrients WHERE Name = $1", nutrient.Name)
-		if err != nil {
-			log.Println("Error while querying Nutrients table")
-			log.Println(err)
-			tx.Rollback()
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		var nutrientID int64
-		if result.Next() {
-			err = result.Scan(&nutrientID)
-			if err != nil {
-				log.Println("Error while scanning nutrientID")
-				log.Println(err)
-				tx.Rollback()
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			err = result.Close()
-			if err != nil {
-				log.Println("Error while closing result of SELECT * FROM Nutrients")
-				log.Println(err)
-				tx.Rollback()
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			resultInsertNutrient, err := tx.QueryContext(r.Context(), "INSERT INTO Nutrients (Name) VALUES ($1) RETURNING NutrientID", nutrient.Name)
-			if err != nil {
-				log.Println("Error while inserting nutrient into Nutrients table")
-				log.Println(err)
-				tx.Rollback()
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			if resultInsertNutrient.Next() {
-				err = resultInsertNutrient.Scan(&nutrientID)
-				if err != nil {
-					log.Println("Error while scanning nutrientID")
-					log.Println(err)
-					tx.Rollback()
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-			}
-			err = resultInsertNutrient.Close()
-			if err != nil {
-				log.Println("Error while closing result of INSERT INTO Nutrients")
-				log.Println(err)
-				tx.Rollback()
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		}
-
-		// create nutrient value
-		_, err = tx.ExecContext(r.Context(), "INSERT INTO Nutrient_Values (IngredientID, NutrientID, AmountPer100g) VALUES ($1, $2, $3)", ingredientID, nutrientID, convertToPerHundredGrams(nutrient.Amount, ingredientRequest.ServingSizeInGrams))
-		if err != nil {
-			log.Println("Error while inserting nutrient value into Nutrient_Values table")
-			log.Println(err)
-			tx.Rollback()
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while creating ingredient", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
+	slog.InfoContext(r.Context(), "Created ingredient", "ingredient_id", ingredientID)
 
-	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	response := CreateIngredientResponse{IngredientID: ingredientID}
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while encoding response", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 }
 
 type Nutrient struct {
 	Name          string  `json:"name"`
+	Unit          string  `json:"unit"`
 	AmountPer100g float64 `json:"amount_per_100g"`
 }
 
 type Ingredient struct {
 	IngredientID int        `json:"ingredient_id"`
 	Name         string     `json:"name"`
+	Barcode      string     `json:"barcode,omitempty"`
+	Brand        string     `json:"brand,omitempty"`
 	Nutrients    []Nutrient `json:"nutrients"`
 }
 
+// normalizeBarcode returns the GTIN-14 form of a barcode accepted by the
+// "gtin" tag, or an empty string when no barcode was given.
+func normalizeBarcode(code string) string {
+	normalized, _ := barcode.Normalize(code)
+	return normalized
+}
+
 // '/api/ingredients/{id}'
 func (i *IngredientHandler) GetIngredientHandle(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-
-	result, err := i.db.Query("SELECT IngredientID, Name FROM Ingredients WHERE IngredientID = $1", vars["id"])
+	ingredientID, err := pathID(r, "id")
 	if err != nil {
-		log.Println("Error while querying Ingredients table")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while parsing ingredientID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
 		return
 	}
 
-	var ingredientID int
-	var ingredientName string
-	if result.Next() {
-		err = result.Scan(&ingredientID, &ingredientName)
-		if err != nil {
-			log.Println("Error while scanning ingredient")
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		http.Error(w, "Ingredient not found", http.StatusNotFound)
+	ingredient, err := i.ingredients.GetIngredient(r.Context(), ingredientID)
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errIngredientNotFound)
 		return
 	}
-	err = result.Close()
 	if err != nil {
-		log.Println("Error while closing result of SELECT * FROM Ingredients")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while getting ingredient", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 
-	// get nutrient name from Nutrients table and AmountPer100g from Nutrient_Values table
-	result, err = i.db.Query("SELECT Nutrients.Name, Nutrient_Values.AmountPer100g FROM Nutrients INNER JOIN Nutrient_Values ON Nutrients.NutrientID = Nutrient_Values.NutrientID WHERE Nutrient_Values.IngredientID = $1", ingredientID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(newIngredientResponse(ingredient))
 	if err != nil {
-		log.Println("Error while querying Nutrients and Nutrient_Values tables")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var nutrients []Nutrient
-	for result.Next() {
-		var nutrientName string
-		var amountPer100g float64
-		err = result.Scan(&nutrientName, &amountPer100g)
-		if err != nil {
-			log.Println("Error while scanning nutrient")
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		nutrients = append(nutrients, Nutrient{Name: nutrientName, AmountPer100g: amountPer100g})
+		writeServerError(w, r, err)
+		return
 	}
+}
 
-	err = result.Close()
+// GET /api/ingredients/by-barcode/{code}
+func (i *IngredientHandler) GetIngredientByBarcodeHandle(w http.ResponseWriter, r *http.Request) {
+	code, err := barcode.Normalize(mux.Vars(r)["code"])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	ingredient, err := i.ingredients.GetIngredientByBarcode(r.Context(), code)
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errIngredientNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while getting ingredient by barcode", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	response := Ingredient{IngredientID: ingredientID, Name: ingredientName, Nutrients: nutrients}
-	err = json.NewEncoder(w).Encode(response)
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(newIngredientResponse(ingredient))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServerError(w, r, err)
 		return
 	}
 }
 
+func newIngredientResponse(ingredient *store.Ingredient) Ingredient {
+	response := Ingredient{IngredientID: int(ingredient.IngredientID), Name: ingredient.Name, Barcode: ingredient.Barcode, Brand: ingredient.Brand}
+	for _, nutrient := range ingredient.Nutrients {
+		response.Nutrients = append(response.Nutrients, Nutrient{Name: nutrient.Name, Unit: nutrient.Unit, AmountPer100g: nutrient.AmountPer100g})
+	}
+	return response
+}
+
+type ListIngredientsResponse struct {
+	Ingredients []Ingredient `json:"ingredients"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
+}
+
+// GET /api/ingredients?name_prefix=&has_nutrient=&sort=&limit=&cursor=
+func (i *IngredientHandler) ListIngredientsHandle(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r)
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	query := r.URL.Query()
+	hasNutrient := query.Get("has_nutrient")
+	if canonical, ok := catalog.Lookup(hasNutrient); ok {
+		hasNutrient = canonical.Name
+	}
+	page, err := i.ingredients.ListIngredients(r.Context(), store.IngredientFilter{
+		NamePrefix:  query.Get("name_prefix"),
+		HasNutrient: hasNutrient,
+		Sort:        query.Get("sort"),
+		Limit:       limit,
+		Cursor:      query.Get("cursor"),
+	})
+	if errors.Is(err, store.ErrInvalidSort) {
+		problem.Write(w, errInvalidParameter.With("sort must be one of name, -name, id or -id"))
+		return
+	}
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while listing ingredients", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	response := ListIngredientsResponse{Ingredients: []Ingredient{}, NextCursor: page.NextCursor}
+	for j := range page.Ingredients {
+		response.Ingredients = append(response.Ingredients, newIngredientResponse(&page.Ingredients[j]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&response)
+}
+
+// UpdateIngredientRequest replaces the name, barcode, brand and complete
+// nutrient list. Leaving barcode or brand out clears them.
+type UpdateIngredientRequest struct {
+	Name               string           `json:"name" validate:"required,notblank,max=255"`
+	Barcode            string           `json:"barcode" validate:"omitempty,gtin"`
+	Brand              string           `json:"brand" validate:"max=255"`
+	ServingSizeInGrams float64          `json:"serving_size_in_grams" validate:"gte=0,lte=99999999.99"`
+	Nutrients          []NutrientAmount `json:"nutrients" validate:"required,dive"`
+}
+
 // PUT /api/ingredients/{id}
 func (i *IngredientHandler) UpdateIngredientHandle(w http.ResponseWriter, r *http.Request) {
+	ingredientID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing ingredientID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	var updateRequest UpdateIngredientRequest
+	if !decodeRequest(w, r, &updateRequest) {
+		return
+	}
+	nutrients, err := toNutrientValues(updateRequest.Nutrients, updateRequest.ServingSizeInGrams)
+	if err != nil {
+		problem.Write(w, nutrientsProblem(err))
+		return
+	}
+
+	ingredient, err := i.ingredients.ReplaceIngredient(r.Context(), &store.Ingredient{
+		IngredientID: ingredientID,
+		Name:         updateRequest.Name,
+		Barcode:      normalizeBarcode(updateRequest.Barcode),
+		Brand:        strings.TrimSpace(updateRequest.Brand),
+		Nutrients:    nutrients,
+	})
+	if errors.Is(err, store.ErrNutrientNotFound) {
+		problem.Write(w, errUnknownNutrient)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errIngredientNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientExists) {
+		problem.Write(w, errIngredientExists)
+		return
+	}
+	if errors.Is(err, store.ErrBarcodeExists) {
+		problem.Write(w, errBarcodeExists)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientIsRecipe) {
+		problem.Write(w, errIngredientIsRecipe)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while replacing ingredient", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newIngredientResponse(ingredient))
+}
+
+// PatchIngredientRequest changes the listed nutrient amounts only.
+type PatchIngredientRequest struct {
+	ServingSizeInGrams float64          `json:"serving_size_in_grams" validate:"gte=0,lte=99999999.99"`
+	Nutrients          []NutrientAmount `json:"nutrients" validate:"required,min=1,dive"`
+}
+
+// PATCH /api/ingredients/{id}
+func (i *IngredientHandler) PatchIngredientHandle(w http.ResponseWriter, r *http.Request) {
+	ingredientID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing ingredientID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	var patchRequest PatchIngredientRequest
+	if !decodeRequest(w, r, &patchRequest) {
+		return
+	}
+	nutrients, err := toNutrientValues(patchRequest.Nutrients, patchRequest.ServingSizeInGrams)
+	if err != nil {
+		problem.Write(w, nutrientsProblem(err))
+		return
+	}
+
+	ingredient, err := i.ingredients.SetIngredientNutrients(r.Context(), ingredientID, nutrients)
+	if errors.Is(err, store.ErrNutrientNotFound) {
+		problem.Write(w, errUnknownNutrient)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errIngredientNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientIsRecipe) {
+		problem.Write(w, errIngredientIsRecipe)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while updating ingredient nutrients", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newIngredientResponse(ingredient))
 }
 
 type DeleteIngredientResponse struct {
@@ -295,40 +408,35 @@ type DeleteIngredientResponse struct {
 
 // DELETE /api/ingredients/{id}
 func (i *IngredientHandler) DeleteIngredientHandle(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-
-	rows, err := i.db.QueryContext(r.Context(), "DELETE FROM Ingredients WHERE IngredientID = $1 RETURNING IngredientID, Name", vars["id"])
-	defer rows.Close()
+	ingredientID, err := pathID(r, "id")
 	if err != nil {
-		log.Println("Error while deleting ingredient from Ingredients table")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while parsing ingredientID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
 		return
 	}
 
-	var ingredientID int64
-	var ingredientName string
-	if rows.Next() {
-		err = rows.Scan(&ingredientID, &ingredientName)
-		if err != nil {
-			log.Println("Error while scanning ingredient")
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		http.Error(w, "Ingredient not found", http.StatusNotFound)
+	ingredient, err := i.ingredients.DeleteIngredient(r.Context(), ingredientID)
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errIngredientNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientInUse) {
+		problem.Write(w, errIngredientInUse)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while deleting ingredient", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	response := DeleteIngredientResponse{IngredientID: ingredientID, Name: ingredientName}
+	w.WriteHeader(http.StatusOK)
+	response := DeleteIngredientResponse{IngredientID: ingredient.IngredientID, Name: ingredient.Name}
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
-		log.Println("Error while encoding response")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while encoding response", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 	return
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// createIngredient creates an ingredient without nutrients through the API
+// and returns its ID.
+func (s *testServer) createIngredient(t *testing.T, token string, name string) int64 {
+	t.Helper()
+	var created CreateIngredientResponse
+	decode(t, s.do(t, token, "POST", "/api/ingredients", map[string]any{"name": name, "nutrients": []any{}}), http.StatusCreated, &created)
+	return created.IngredientID
+}
+
+func TestIngredientCRUD(t *testing.T) {
+	s := newTestServer(t)
+
+	var created CreateIngredientResponse
+	decode(t, s.do(t, s.alice, "POST", "/api/ingredients", map[string]any{
+		"name":                  "Oats",
+		"serving_size_in_grams": 40,
+		"nutrients": []map[string]any{
+			{"name": "protein", "amount": 5.2},
+			{"name": "Calories", "amount": 150},
+		},
+	}), http.StatusCreated, &created)
+	path := fmt.Sprintf("/api/ingredients/%d", created.IngredientID)
+
+	var ingredient Ingredient
+	decode(t, s.do(t, s.alice, "GET", path, nil), http.StatusOK, &ingredient)
+	// names resolved through the catalog and amounts scaled from 40 g to 100 g
+	want := []Nutrient{{Name: "Energy", Unit: "kcal", AmountPer100g: 375}, {Name: "Protein", Unit: "g", AmountPer100g: 13}}
+	if ingredient.Name != "Oats" || fmt.Sprint(ingredient.Nutrients) != fmt.Sprint(want) {
+		t.Fatalf("GET ingredient = %+v, want nutrients %+v", ingredient, want)
+	}
+
+	decode(t, s.do(t, s.alice, "PUT", path, map[string]any{
+		"name":      "Rolled oats",
+		"nutrients": []map[string]any{{"name": "Fat", "amount": 6.5}},
+	}), http.StatusOK, &ingredient)
+	if ingredient.Name != "Rolled oats" || len(ingredient.Nutrients) != 1 || ingredient.Nutrients[0].Name != "Fat" {
+		t.Errorf("PUT ingredient = %+v", ingredient)
+	}
+
+	decode(t, s.do(t, s.alice, "PATCH", path, map[string]any{
+		"nutrients": []map[string]any{{"name": "Fiber", "amount": 10}},
+	}), http.StatusOK, &ingredient)
+	if len(ingredient.Nutrients) != 2 {
+		t.Errorf("PATCH ingredient nutrients = %+v, want Fat and Fiber", ingredient.Nutrients)
+	}
+
+	var list ListIngredientsResponse
+	decode(t, s.do(t, s.alice, "GET", "/api/ingredients?name_prefix=roll", nil), http.StatusOK, &list)
+	if len(list.Ingredients) != 1 || list.Ingredients[0].Name != "Rolled oats" {
+		t.Errorf("GET /api/ingredients?name_prefix=roll = %+v", list.Ingredients)
+	}
+
+	decode(t, s.do(t, s.alice, "DELETE", path, nil), http.StatusOK, nil)
+	expectProblem(t, s.do(t, s.alice, "GET", path, nil), http.StatusNotFound, "ingredient_not_found")
+	expectProblem(t, s.do(t, s.alice, "DELETE", path, nil), http.StatusNotFound, "ingredient_not_found")
+}
+
+func TestIngredientErrors(t *testing.T) {
+	s := newTestServer(t)
+	oats := s.createIngredient(t, s.alice, "Oats")
+	rye := s.createIngredient(t, s.alice, "Rye")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"unknown ingredient", "GET", "/api/ingredients/999", nil, http.StatusNotFound, "ingredient_not_found"},
+		{"replace unknown ingredient", "PUT", "/api/ingredients/999", map[string]any{"name": "Barley", "nutrients": []any{}}, http.StatusNotFound, "ingredient_not_found"},
+		{"taken name", "POST", "/api/ingredients", map[string]any{"name": "Oats", "nutrients": []any{}}, http.StatusConflict, "ingredient_exists"},
+		{"rename to a taken name", "PUT", fmt.Sprintf("/api/ingredients/%d", rye), map[string]any{"name": "Oats", "nutrients": []any{}}, http.StatusConflict, "ingredient_exists"},
+		{"unknown nutrient", "POST", "/api/ingredients", map[string]any{"name": "Barley", "nutrients": []map[string]any{{"name": "Unobtainium", "amount": 1}}}, http.StatusUnprocessableEntity, "unknown_nutrient"},
+		{"nutrient named twice", "PATCH", fmt.Sprintf("/api/ingredients/%d", oats), map[string]any{"nutrients": []map[string]any{{"name": "Fat", "amount": 1}, {"name": "fats", "amount": 2}}}, http.StatusBadRequest, "duplicate_nutrient"},
+		{"negative amount", "POST", "/api/ingredients", map[string]any{"name": "Barley", "nutrients": []map[string]any{{"name": "Fat", "amount": -1}}}, http.StatusUnprocessableEntity, "validation_failed"},
+		{"amount too large for its column", "POST", "/api/ingredients", map[string]any{"name": "Barley", "nutrients": []map[string]any{{"name": "Fat", "amount": 1e9}}}, http.StatusUnprocessableEntity, "validation_failed"},
+		{"invalid barcode", "POST", "/api/ingredients", map[string]any{"name": "Barley", "barcode": "123", "nutrients": []any{}}, http.StatusUnprocessableEntity, "validation_failed"},
+		{"invalid sort", "GET", "/api/ingredients?sort=calories", nil, http.StatusBadRequest, "invalid_parameter"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectProblem(t, s.do(t, s.alice, test.method, test.path, test.body), test.status, test.code)
+		})
+	}
+}
+
+// A tiny serving size scales a valid amount past what the column can hold.
+func TestIngredientScaledAmountTooLarge(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(t, s.alice, "POST", "/api/ingredients", map[string]any{
+		"name":                  "Salt",
+		"serving_size_in_grams": 0.001,
+		"nutrients":             []map[string]any{{"name": "Fat", "amount": 0}, {"name": "Sodium", "amount": 1e6}},
+	})
+	p := expectProblem(t, rec, http.StatusUnprocessableEntity, "validation_failed")
+	if len(p.Errors) != 1 || p.Errors[0].Field != "nutrients[1].amount" {
+		t.Errorf("field errors = %+v, want one for nutrients[1].amount", p.Errors)
+	}
+}
+
+func TestIngredientBarcode(t *testing.T) {
+	s := newTestServer(t)
+
+	body := map[string]any{"name": "Pen", "barcode": "4006381333931", "brand": "Stabilo", "nutrients": []any{}}
+	decode(t, s.do(t, s.alice, "POST", "/api/ingredients", body), http.StatusCreated, nil)
+
+	var ingredient Ingredient
+	// looked up by its EAN-13 with the GTIN-14 stored
+	decode(t, s.do(t, s.alice, "GET", "/api/ingredients/by-barcode/4006381333931", nil), http.StatusOK, &ingredient)
+	if ingredient.Name != "Pen" || ingredient.Barcode != "04006381333931" || ingredient.Brand != "Stabilo" {
+		t.Errorf("GET by barcode = %+v", ingredient)
+	}
+
+	body["name"] = "Other pen"
+	expectProblem(t, s.do(t, s.alice, "POST", "/api/ingredients", body), http.StatusConflict, "barcode_exists")
+	expectProblem(t, s.do(t, s.alice, "GET", "/api/ingredients/by-barcode/5901234123457", nil), http.StatusNotFound, "ingredient_not_found")
+}
+
+// Ingredients are shared between users, unlike meals.
+func TestIngredientsAreShared(t *testing.T) {
+	s := newTestServer(t)
+	oats := s.createIngredient(t, s.alice, "Oats")
+
+	var ingredient Ingredient
+	decode(t, s.do(t, s.bob, "GET", fmt.Sprintf("/api/ingredients/%d", oats), nil), http.StatusOK, &ingredient)
+	if ingredient.Name != "Oats" {
+		t.Errorf("GET ingredient as another user = %+v", ingredient)
+	}
+}
+
+func TestToNutrientValues(t *testing.T) {
+	nutrients := []NutrientAmount{{Name: "Protein (g)", Amount: 5}, {Name: "kcal", Amount: 150}}
+
+	tests := []struct {
+		servingSizeInGrams float64
+		want               []float64
+	}{
+		{40, []float64{12.5, 375}},
+		{250, []float64{2, 60}},
+		// no serving size means the amounts already are per 100 g
+		{0, []float64{5, 150}},
+	}
+	for _, test := range tests {
+		values, err := toNutrientValues(nutrients, test.servingSizeInGrams)
+		if err != nil {
+			t.Fatalf("toNutrientValues(%v): %v", test.servingSizeInGrams, err)
+		}
+		if len(values) != 2 || values[0].Name != "Protein" || values[1].Name != "Energy" {
+			t.Fatalf("toNutrientValues(%v) = %+v", test.servingSizeInGrams, values)
+		}
+		for i, want := range test.want {
+			if values[i].AmountPer100g != want {
+				t.Errorf("toNutrientValues(%v)[%d] = %v per 100 g, want %v", test.servingSizeInGrams, i, values[i].AmountPer100g, want)
+			}
+		}
+	}
+}
@@ -1,31 +1,33 @@
 package handlers
 
 import (
-	"database/sql"
+	"assignment2/problem"
+	"assignment2/store"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
-
-	"github.com/gorilla/mux"
 )
 
 type MealHandler struct {
-	db *sql.DB
+	meals store.MealStore
 }
 
-func NewMealHandler(db *sql.DB) *MealHandler {
-	return &MealHandler{db: db}
+func NewMealHandler(meals store.MealStore) *MealHandler {
+	return &MealHandler{meals: meals}
 }
 
 type CreateMealRequest struct {
-	Name        string    `json:"name" validate:"required"`
+	Name        string    `json:"name" validate:"required,notblank,max=255"`
 	DateTime    time.Time `json:"date_time" validate:"required"`
 	Ingredients []struct {
-		IngredientID  int64   `json:"ingredient_id"`
-		AmountInGrams float64 `json:"amount_in_grams"`
-	} `json:"ingredients"`
+		IngredientID  int64   `json:"ingredient_id" validate:"gt=0,lte=2147483647"`
+		AmountInGrams float64 `json:"amount_in_grams" validate:"gt=0,lte=99999999.99"`
+	} `json:"ingredients" validate:"dive"`
 }
 
 type CreateMealResponse struct {
@@ -33,71 +35,36 @@ type CreateMealResponse struct {
 }
 
 func (m *MealHandler) CreateMealHandle(w http.ResponseWriter, r *http.Request) {
-	var mealRequest *CreateMealRequest
-	err := json.NewDecoder(r.Body).Decode(&mealRequest)
-	if err != nil {
-		log.Println("Error while decoding request body")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var mealRequest CreateMealRequest
+	if !decodeRequest(w, r, &mealRequest) {
 		return
 	}
 
-	// create transaction
-	tx, err := m.db.BeginTx(r.Context(), nil)
-	if err != nil {
-		log.Println("Error while creating transaction")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	meal := &store.Meal{
+		UserID: currentUserID(r),
+		Name:   mealRequest.Name,
+		Date:   mealRequest.DateTime,
+		Time:   mealRequest.DateTime,
 	}
-
-	// insert into Meals table
-	rows, err := tx.QueryContext(r.Context(), "INSERT INTO Meals (Name, Date, Time) VALUES ($1, $2, $3) RETURNING MealID", mealRequest.Name, mealRequest.DateTime, mealRequest.DateTime)
-	if err != nil {
-		log.Println("Error while inserting into Meals table")
-		log.Println(err)
-		tx.Rollback()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	for _, ingredient := range mealRequest.Ingredients {
+		meal.Ingredients = append(meal.Ingredients, store.MealIngredient{
+			IngredientID:  ingredient.IngredientID,
+			AmountInGrams: ingredient.AmountInGrams,
+		})
 	}
 
-	var mealID int64
-	if rows.Next() {
-		err = rows.Scan(&mealID)
-		if err != nil {
-			log.Println("Error while scanning mealID")
-			log.Println(err)
-			tx.Rollback()
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	err = rows.Close()
-	if err != nil {
-		log.Println("Error while closing result of INSERT INTO Meals")
-		log.Println(err)
-		tx.Rollback()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	mealID, err := m.meals.CreateMeal(r.Context(), meal)
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errUnknownIngredient)
 		return
 	}
-
-	// insert into MealIngredients table
-	for _, ingredient := range mealRequest.Ingredients {
-		_, err = tx.ExecContext(r.Context(), "INSERT INTO Meal_Ingredients (MealID, IngredientID, QuantityInGrams) VALUES ($1, $2, $3)", mealID, ingredient.IngredientID, ingredient.AmountInGrams)
-		if err != nil {
-			log.Println("Error while inserting into MealIngredients table")
-			log.Println(err)
-			tx.Rollback()
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	if errors.Is(err, store.ErrMealIngredientExists) {
+		problem.Write(w, errDuplicateMealIngredient)
+		return
 	}
-
-	err = tx.Commit()
 	if err != nil {
-		log.Println("Error while committing transaction")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while creating meal", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 
@@ -112,6 +79,7 @@ type GetMealResponse struct {
 	Name        string    `json:"name" validate:"required"`
 	Date        time.Time `json:"date" validate:"required"`
 	Time        time.Time `json:"time"`
+	UpdatedAt   time.Time `json:"updated_at"`
 	Ingredients []struct {
 		IngredientID  int64   `json:"ingredient_id"`
 		AmountInGrams float64 `json:"amount_in_grams"`
@@ -119,99 +87,207 @@ type GetMealResponse struct {
 	} `json:"ingredients"`
 }
 
+func newMealResponse(meal *store.Meal) *GetMealResponse {
+	response := &GetMealResponse{MealID: meal.MealID, Name: meal.Name, Date: meal.Date, Time: meal.Time, UpdatedAt: meal.UpdatedAt}
+	for _, ingredient := range meal.Ingredients {
+		response.Ingredients = append(response.Ingredients, struct {
+			IngredientID  int64   `json:"ingredient_id"`
+			AmountInGrams float64 `json:"amount_in_grams"`
+			Name          string  `json:"name"`
+		}{IngredientID: ingredient.IngredientID, AmountInGrams: ingredient.AmountInGrams, Name: ingredient.Name})
+	}
+	return response
+}
+
+// mealETag identifies a version of the meal; clients send it back in
+// If-Match to make sure they do not overwrite someone else's change.
+func mealETag(meal *store.Meal) string {
+	return `"` + strconv.FormatInt(meal.UpdatedAt.UnixMicro(), 10) + `"`
+}
+
+// ifMatch returns the UpdatedAt named by the If-Match header, or the zero
+// time when the header is absent or "*".
+func ifMatch(r *http.Request) (time.Time, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" || header == "*" {
+		return time.Time{}, nil
+	}
+	micros, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("If-Match must be an ETag returned by this API")
+	}
+	return time.UnixMicro(micros).UTC(), nil
+}
+
+func writeMeal(w http.ResponseWriter, status int, meal *store.Meal) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", mealETag(meal))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newMealResponse(meal))
+}
+
 // GET /api/meals/{id}
 func (m *MealHandler) GetMealHandle(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+	mealID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing mealID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
 
-	result, err := m.db.Query("SELECT MealID, Name, Date, Time FROM Meals WHERE MealID = $1", vars["id"])
+	meal, err := m.meals.GetMeal(r.Context(), currentUserID(r), mealID)
+	if errors.Is(err, store.ErrMealNotFound) {
+		problem.Write(w, errMealNotFound)
+		return
+	}
 	if err != nil {
-		log.Println("Error while querying Meals table")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var mealID int64
-	var mealName string
-	var mealDate time.Time
-	var mealTime time.Time
-	if result.Next() {
-		err = result.Scan(&mealID, &mealName, &mealDate, &mealTime)
-		if err != nil {
-			log.Println("Error while scanning meal")
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	err = result.Close()
+		slog.ErrorContext(r.Context(), "Error while getting meal", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeMeal(w, http.StatusOK, meal)
+	return
+}
+
+type NutrientTotalResponse struct {
+	NutrientID int64   `json:"nutrient_id"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	Amount     float64 `json:"amount"`
+}
+
+type IngredientNutritionResponse struct {
+	IngredientID  int64                   `json:"ingredient_id"`
+	Name          string                  `json:"name"`
+	AmountInGrams float64                 `json:"amount_in_grams"`
+	Nutrients     []NutrientTotalResponse `json:"nutrients"`
+}
+
+type MealNutritionResponse struct {
+	MealID      int64                         `json:"meal_id"`
+	Totals      []NutrientTotalResponse       `json:"totals"`
+	Ingredients []IngredientNutritionResponse `json:"ingredients"`
+}
+
+// roundAmount rounds computed amounts to the two decimals nutrient values are
+// stored with.
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func newNutrientTotalsResponse(totals []store.NutrientTotal) []NutrientTotalResponse {
+	response := []NutrientTotalResponse{}
+	for _, total := range totals {
+		response = append(response, NutrientTotalResponse{NutrientID: total.NutrientID, Name: total.Name, Unit: total.Unit, Amount: roundAmount(total.Amount)})
+	}
+	return response
+}
+
+// GET /api/meals/{id}/nutrition
+func (m *MealHandler) GetMealNutritionHandle(w http.ResponseWriter, r *http.Request) {
+	mealID, err := pathID(r, "id")
 	if err != nil {
-		log.Println("Error while closing result for SELECT Meals query")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while parsing mealID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
 		return
 	}
 
-	// get ingredients
-	result, err = m.db.Query("SELECT IngredientID, QuantityInGrams FROM Meal_Ingredients WHERE MealID = $1", mealID)
+	nutrition, err := m.meals.GetMealNutrition(r.Context(), currentUserID(r), mealID)
+	if errors.Is(err, store.ErrMealNotFound) {
+		problem.Write(w, errMealNotFound)
+		return
+	}
 	if err != nil {
-		log.Println("Error while querying MealIngredients table")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while computing meal nutrition", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 
-	var ingredients []struct {
-		IngredientID  int64   `json:"ingredient_id"`
-		AmountInGrams float64 `json:"amount_in_grams"`
-		Name          string  `json:"name"`
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newMealNutritionResponse(nutrition))
+}
+
+func newMealNutritionResponse(nutrition *store.MealNutrition) *MealNutritionResponse {
+	response := &MealNutritionResponse{
+		MealID:      nutrition.MealID,
+		Totals:      newNutrientTotalsResponse(nutrition.Totals),
+		Ingredients: []IngredientNutritionResponse{},
+	}
+	for _, ingredient := range nutrition.Ingredients {
+		response.Ingredients = append(response.Ingredients, IngredientNutritionResponse{
+			IngredientID:  ingredient.IngredientID,
+			Name:          ingredient.Name,
+			AmountInGrams: ingredient.AmountInGrams,
+			Nutrients:     newNutrientTotalsResponse(ingredient.Nutrients),
+		})
 	}
+	return response
+}
 
-	for result.Next() {
-		var ingredientID int64
-		var amountInGrams float64
-		err = result.Scan(&ingredientID, &amountInGrams)
-		if err != nil {
-			log.Println("Error while scanning ingredient")
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// get ingredient name
-		var ingredientName string
-		err = m.db.QueryRow("SELECT Name FROM Ingredients WHERE IngredientID = $1", ingredientID).Scan(&ingredientName)
-		if err != nil {
-			log.Println("Error while querying Ingredients table")
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		ingredients = append(ingredients, struct {
-			IngredientID  int64   `json:"ingredient_id"`
-			AmountInGrams float64 `json:"amount_in_grams"`
-			Name          string  `json:"name"`
-		}{IngredientID: ingredientID, AmountInGrams: amountInGrams, Name: ingredientName})
+type ListMealsResponse struct {
+	Meals      []*GetMealResponse `json:"meals"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// GET /api/meals?from=&to=&sort=&limit=&cursor=
+func (m *MealHandler) ListMealsHandle(w http.ResponseWriter, r *http.Request) {
+	from, err := queryDate(r, "from")
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	to, err := queryDate(r, "to")
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		problem.Write(w, errInvalidParameter.With("to must not be before from"))
+		return
+	}
+	limit, err := queryLimit(r)
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
 	}
 
-	err = result.Close()
+	query := r.URL.Query()
+	page, err := m.meals.ListMeals(r.Context(), currentUserID(r), store.MealFilter{
+		From:   from,
+		To:     to,
+		Sort:   query.Get("sort"),
+		Limit:  limit,
+		Cursor: query.Get("cursor"),
+	})
+	if errors.Is(err, store.ErrInvalidSort) {
+		problem.Write(w, errInvalidParameter.With("sort must be date or -date"))
+		return
+	}
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
 	if err != nil {
-		log.Println("Error while closing result for SELECT Meal_Ingredients query")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while listing meals", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 
+	response := ListMealsResponse{Meals: []*GetMealResponse{}, NextCursor: page.NextCursor}
+	for i := range page.Meals {
+		response.Meals = append(response.Meals, newMealResponse(&page.Meals[i]))
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(&GetMealResponse{MealID: mealID, Name: mealName, Date: mealDate, Time: mealTime, Ingredients: ingredients})
-	return
+	json.NewEncoder(w).Encode(&response)
 }
 
 type AddIngredientToMealRequest struct {
-	IngredientID  int64   `json:"ingredient_id"`
-	AmountInGrams float64 `json:"amount_in_grams"`
+	IngredientID  int64   `json:"ingredient_id" validate:"gt=0,lte=2147483647"`
+	AmountInGrams float64 `json:"amount_in_grams" validate:"gt=0,lte=99999999.99"`
 }
 
 type AddIngredientToMealResponse struct {
@@ -220,30 +296,44 @@ type AddIngredientToMealResponse struct {
 	AmountInGrams float64 `json:"amount_in_grams"`
 }
 
-// PUT /api/meals/{id}/ingredients
+// POST /api/meals/{id}/ingredients
 func (m *MealHandler) AddIngredientToMealHandle(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	mealID, err := strconv.ParseInt(vars["id"], 10, 64)
+	mealID, err := pathID(r, "id")
 	if err != nil {
-		log.Println("Error while parsing mealID")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while parsing mealID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
 		return
 	}
 
-	var addIngredientRequest *AddIngredientToMealRequest
-	err = json.NewDecoder(r.Body).Decode(&addIngredientRequest)
+	var addIngredientRequest AddIngredientToMealRequest
+	if !decodeRequest(w, r, &addIngredientRequest) {
+		return
+	}
 
-	_, err = m.db.ExecContext(r.Context(), "INSERT INTO Meal_Ingredients (MealID, IngredientID, QuantityInGrams) VALUES ($1, $2, $3)", mealID, addIngredientRequest.IngredientID, addIngredientRequest.AmountInGrams)
+	err = m.meals.AddIngredientToMeal(r.Context(), currentUserID(r), mealID, store.MealIngredient{
+		IngredientID:  addIngredientRequest.IngredientID,
+		AmountInGrams: addIngredientRequest.AmountInGrams,
+	})
+	if errors.Is(err, store.ErrMealNotFound) {
+		problem.Write(w, errMealNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errUnknownIngredient)
+		return
+	}
+	if errors.Is(err, store.ErrMealIngredientExists) {
+		problem.Write(w, errMealIngredientExists)
+		return
+	}
 	if err != nil {
-		log.Println("Error while inserting into MealIngredients table")
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error while adding ingredient to meal", "error", err)
+		writeServerError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(&AddIngredientToMealResponse{
 		MealID:        mealID,
 		IngredientID:  addIngredientRequest.IngredientID,
@@ -253,11 +343,180 @@ func (m *MealHandler) AddIngredientToMealHandle(w http.ResponseWriter, r *http.R
 
 }
 
+// DELETE /api/meals/{id}/ingredients/{ingredient_id}
 func (m *MealHandler) RemoveIngredientFromMealHandle(w http.ResponseWriter, r *http.Request) {
+	mealID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing mealID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	ingredientID, err := pathID(r, "ingredient_id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing ingredientID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	ifUpdatedAt, err := ifMatch(r)
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	meal, err := m.meals.RemoveIngredientFromMeal(r.Context(), currentUserID(r), mealID, ingredientID, ifUpdatedAt)
+	if errors.Is(err, store.ErrMealNotFound) {
+		problem.Write(w, errMealNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrMealIngredientNotFound) {
+		problem.Write(w, errMealIngredientNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrMealModified) {
+		problem.Write(w, errMealModified)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while removing ingredient from meal", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeMeal(w, http.StatusOK, meal)
 }
 
+type SetMealIngredientRequest struct {
+	AmountInGrams float64 `json:"amount_in_grams" validate:"gt=0,lte=99999999.99"`
+}
+
+// PUT /api/meals/{id}/ingredients/{ingredient_id}
 func (m *MealHandler) UpdateIngredientInMealHandle(w http.ResponseWriter, r *http.Request) {
+	mealID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing mealID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	ingredientID, err := pathID(r, "ingredient_id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing ingredientID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	var setRequest SetMealIngredientRequest
+	if !decodeRequest(w, r, &setRequest) {
+		return
+	}
+
+	created, err := m.meals.SetMealIngredient(r.Context(), currentUserID(r), mealID, store.MealIngredient{
+		IngredientID:  ingredientID,
+		AmountInGrams: setRequest.AmountInGrams,
+	})
+	if errors.Is(err, store.ErrMealNotFound) {
+		problem.Write(w, errMealNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrIngredientNotFound) {
+		problem.Write(w, errIngredientNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while setting meal ingredient", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&AddIngredientToMealResponse{
+		MealID:        mealID,
+		IngredientID:  ingredientID,
+		AmountInGrams: setRequest.AmountInGrams,
+	})
+}
+
+type UpdateMealRequest struct {
+	Name     *string    `json:"name" validate:"omitnil,notblank,max=255"`
+	DateTime *time.Time `json:"date_time" validate:"omitnil,required"`
 }
 
+// PATCH /api/meals/{id}
+func (m *MealHandler) UpdateMealHandle(w http.ResponseWriter, r *http.Request) {
+	mealID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing mealID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	ifUpdatedAt, err := ifMatch(r)
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	var updateRequest UpdateMealRequest
+	if !decodeRequest(w, r, &updateRequest) {
+		return
+	}
+	if updateRequest.Name == nil && updateRequest.DateTime == nil {
+		problem.Write(w, errNothingToUpdate)
+		return
+	}
+
+	meal, err := m.meals.UpdateMeal(r.Context(), currentUserID(r), mealID, store.MealUpdate{
+		Name:     updateRequest.Name,
+		DateTime: updateRequest.DateTime,
+	}, ifUpdatedAt)
+	if errors.Is(err, store.ErrMealNotFound) {
+		problem.Write(w, errMealNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrMealModified) {
+		problem.Write(w, errMealModified)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while updating meal", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeMeal(w, http.StatusOK, meal)
+}
+
+// DELETE /api/meals/{id}
 func (m *MealHandler) DeleteMealHandle(w http.ResponseWriter, r *http.Request) {
+	mealID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing mealID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	ifUpdatedAt, err := ifMatch(r)
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	meal, err := m.meals.DeleteMeal(r.Context(), currentUserID(r), mealID, ifUpdatedAt)
+	if errors.Is(err, store.ErrMealNotFound) {
+		problem.Write(w, errMealNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrMealModified) {
+		problem.Write(w, errMealModified)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while deleting meal", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeMeal(w, http.StatusOK, meal)
 }
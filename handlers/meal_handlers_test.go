@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// createMeal creates a meal through the API and returns its ID.
+func (s *testServer) createMeal(t *testing.T, token string, body any) int64 {
+	t.Helper()
+	var created CreateMealResponse
+	decode(t, s.do(t, token, "POST", "/api/meals", body), http.StatusCreated, &created)
+	return created.MealID
+}
+
+func TestMealCRUD(t *testing.T) {
+	s := newTestServer(t)
+	oats := s.createIngredient(t, s.alice, "Oats")
+	milk := s.createIngredient(t, s.alice, "Milk")
+
+	mealID := s.createMeal(t, s.alice, map[string]any{
+		"name":        "Breakfast",
+		"date_time":   "2023-11-20T08:30:00Z",
+		"ingredients": []map[string]any{{"ingredient_id": oats, "amount_in_grams": 50}},
+	})
+	path := fmt.Sprintf("/api/meals/%d", mealID)
+
+	rec := s.do(t, s.alice, "POST", path+"/ingredients", map[string]any{"ingredient_id": milk, "amount_in_grams": 200})
+	decode(t, rec, http.StatusCreated, nil)
+
+	rec = s.do(t, s.alice, "PUT", fmt.Sprintf("%s/ingredients/%d", path, milk), map[string]any{"amount_in_grams": 250})
+	decode(t, rec, http.StatusOK, nil)
+
+	rec = s.do(t, s.alice, "GET", path, nil)
+	var meal GetMealResponse
+	decode(t, rec, http.StatusOK, &meal)
+	if meal.Name != "Breakfast" || len(meal.Ingredients) != 2 || meal.Ingredients[1].AmountInGrams != 250 {
+		t.Fatalf("GET meal = %+v", meal)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET meal did not send an ETag")
+	}
+
+	rec = s.do(t, s.alice, "PATCH", path, map[string]any{"name": "Late breakfast"})
+	decode(t, rec, http.StatusOK, &meal)
+	if meal.Name != "Late breakfast" {
+		t.Errorf("PATCH meal name = %q, want %q", meal.Name, "Late breakfast")
+	}
+
+	rec = s.do(t, s.alice, "DELETE", fmt.Sprintf("%s/ingredients/%d", path, oats), nil)
+	decode(t, rec, http.StatusOK, &meal)
+	if len(meal.Ingredients) != 1 || meal.Ingredients[0].IngredientID != milk {
+		t.Errorf("meal ingredients after DELETE = %+v", meal.Ingredients)
+	}
+
+	decode(t, s.do(t, s.alice, "DELETE", path, nil), http.StatusOK, nil)
+	expectProblem(t, s.do(t, s.alice, "GET", path, nil), http.StatusNotFound, "meal_not_found")
+}
+
+func TestMealErrors(t *testing.T) {
+	s := newTestServer(t)
+	oats := s.createIngredient(t, s.alice, "Oats")
+	mealID := s.createMeal(t, s.alice, map[string]any{
+		"name":        "Breakfast",
+		"date_time":   "2023-11-20T08:30:00Z",
+		"ingredients": []map[string]any{{"ingredient_id": oats, "amount_in_grams": 50}},
+	})
+	path := fmt.Sprintf("/api/meals/%d", mealID)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"unknown meal", "GET", "/api/meals/999", nil, http.StatusNotFound, "meal_not_found"},
+		{"non-numeric id", "GET", "/api/meals/abc", nil, http.StatusBadRequest, "invalid_parameter"},
+		{"empty body", "POST", "/api/meals", nil, http.StatusBadRequest, "empty_body"},
+		{"malformed body", "POST", "/api/meals", "{", http.StatusBadRequest, "invalid_json"},
+		{"missing name", "POST", "/api/meals", map[string]any{"date_time": "2023-11-20T08:30:00Z"}, http.StatusUnprocessableEntity, "validation_failed"},
+		{"unknown ingredient", "POST", path + "/ingredients", map[string]any{"ingredient_id": oats + 100, "amount_in_grams": 10}, http.StatusUnprocessableEntity, "unknown_ingredient"},
+		{"ingredient added twice", "POST", path + "/ingredients", map[string]any{"ingredient_id": oats, "amount_in_grams": 10}, http.StatusConflict, "meal_ingredient_exists"},
+		{"ingredient not in meal", "DELETE", fmt.Sprintf("%s/ingredients/%d", path, oats+1), nil, http.StatusNotFound, "meal_ingredient_not_found"},
+		{"nothing to update", "PATCH", path, map[string]any{}, http.StatusBadRequest, "nothing_to_update"},
+		{"amount too large for its column", "PUT", fmt.Sprintf("%s/ingredients/%d", path, oats), map[string]any{"amount_in_grams": 1e12}, http.StatusUnprocessableEntity, "validation_failed"},
+		{"ingredient ID too large for its column", "POST", path + "/ingredients", map[string]any{"ingredient_id": int64(1) << 40, "amount_in_grams": 10}, http.StatusUnprocessableEntity, "validation_failed"},
+		{
+			"duplicate ingredient lines", "POST", "/api/meals",
+			map[string]any{
+				"name":      "Lunch",
+				"date_time": "2023-11-20T12:00:00Z",
+				"ingredients": []map[string]any{
+					{"ingredient_id": oats, "amount_in_grams": 10},
+					{"ingredient_id": oats, "amount_in_grams": 20},
+				},
+			},
+			http.StatusConflict, "duplicate_meal_ingredient",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectProblem(t, s.do(t, s.alice, test.method, test.path, test.body), test.status, test.code)
+		})
+	}
+}
+
+func TestMealIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	mealID := s.createMeal(t, s.alice, map[string]any{"name": "Breakfast", "date_time": "2023-11-20T08:30:00Z"})
+	path := fmt.Sprintf("/api/meals/%d", mealID)
+
+	rec := s.do(t, s.alice, "GET", path, nil)
+	decode(t, rec, http.StatusOK, nil)
+	etag := rec.Header().Get("ETag")
+
+	// the first PATCH changes the version, so the second one is stale
+	for i, want := range []int{http.StatusOK, http.StatusConflict} {
+		req := s.request(t, s.alice, "PATCH", path, map[string]any{"name": fmt.Sprintf("Breakfast %d", i)})
+		req.Header.Set("If-Match", etag)
+		rec := s.serve(req)
+		if rec.Code != want {
+			t.Fatalf("PATCH %d with If-Match: status = %d, want %d; body: %s", i, rec.Code, want, rec.Body)
+		}
+	}
+}
+
+func TestMealsAreScopedToTheirOwner(t *testing.T) {
+	s := newTestServer(t)
+	oats := s.createIngredient(t, s.alice, "Oats")
+	mealID := s.createMeal(t, s.alice, map[string]any{"name": "Breakfast", "date_time": "2023-11-20T08:30:00Z"})
+	path := fmt.Sprintf("/api/meals/%d", mealID)
+
+	tests := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{"GET", path, nil},
+		{"PATCH", path, map[string]any{"name": "Mine now"}},
+		{"DELETE", path, nil},
+		{"POST", path + "/ingredients", map[string]any{"ingredient_id": oats, "amount_in_grams": 10}},
+		{"PUT", fmt.Sprintf("%s/ingredients/%d", path, oats), map[string]any{"amount_in_grams": 10}},
+	}
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			expectProblem(t, s.do(t, s.bob, test.method, test.path, test.body), http.StatusNotFound, "meal_not_found")
+		})
+	}
+
+	var list ListMealsResponse
+	decode(t, s.do(t, s.bob, "GET", "/api/meals", nil), http.StatusOK, &list)
+	if len(list.Meals) != 0 {
+		t.Errorf("GET /api/meals as another user = %d meals, want 0", len(list.Meals))
+	}
+
+	var meal GetMealResponse
+	decode(t, s.do(t, s.alice, "GET", path, nil), http.StatusOK, &meal)
+	if meal.Name != "Breakfast" || len(meal.Ingredients) != 0 {
+		t.Errorf("meal after requests of another user = %+v", meal)
+	}
+}
+
+func TestMealRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+	expectProblem(t, s.do(t, "", "GET", "/api/meals", nil), http.StatusUnauthorized, "missing_token")
+	expectProblem(t, s.do(t, "not-a-token", "GET", "/api/meals", nil), http.StatusUnauthorized, "invalid_token")
+}
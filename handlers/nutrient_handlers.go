@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"assignment2/catalog"
+	"assignment2/store"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+)
+
+type NutrientHandler struct {
+	nutrients store.NutrientStore
+}
+
+func NewNutrientHandler(nutrients store.NutrientStore) *NutrientHandler {
+	return &NutrientHandler{nutrients: nutrients}
+}
+
+type NutrientResponse struct {
+	NutrientID int64    `json:"nutrient_id"`
+	Name       string   `json:"name"`
+	Unit       string   `json:"unit"`
+	Category   string   `json:"category"`
+	Aliases    []string `json:"aliases"`
+}
+
+// GET /api/nutrients lists the catalog nutrients ingredients may use.
+func (n *NutrientHandler) ListNutrientsHandle(w http.ResponseWriter, r *http.Request) {
+	nutrients, err := n.nutrients.ListNutrients(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while listing nutrients", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	response := []NutrientResponse{}
+	for _, nutrient := range nutrients {
+		canonical, ok := catalog.Lookup(nutrient.Name)
+		if !ok || nutrient.Unit == "" {
+			// left over from before the catalog, kept for existing values only
+			continue
+		}
+		aliases := append([]string{}, canonical.Aliases...)
+		response = append(response, NutrientResponse{
+			NutrientID: nutrient.NutrientID,
+			Name:       nutrient.Name,
+			Unit:       nutrient.Unit,
+			Category:   nutrient.Category,
+			Aliases:    aliases,
+		})
+	}
+	sort.Slice(response, func(i, j int) bool {
+		if response[i].Category != response[j].Category {
+			return response[i].Category < response[j].Category
+		}
+		return response[i].Name < response[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&response)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"assignment2/problem"
+	"assignment2/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type RecipeHandler struct {
+	recipes store.RecipeStore
+}
+
+func NewRecipeHandler(recipes store.RecipeStore) *RecipeHandler {
+	return &RecipeHandler{recipes: recipes}
+}
+
+// RecipeRequest describes a recipe. YieldInGrams is the weight of the cooked
+// dish and defaults to the total weight of its ingredients.
+type RecipeRequest struct {
+	Name         string  `json:"name" validate:"required,notblank,max=255"`
+	YieldInGrams float64 `json:"yield_in_grams" validate:"gte=0,lte=99999999.99"`
+	Ingredients  []struct {
+		IngredientID  int64   `json:"ingredient_id" validate:"gt=0,lte=2147483647"`
+		AmountInGrams float64 `json:"amount_in_grams" validate:"gt=0,lte=99999999.99"`
+	} `json:"ingredients" validate:"required,min=1,dive"`
+}
+
+// validate checks the yield that toRecipe derives when none is given; the
+// fields themselves have been checked by decodeRequest.
+func (req *RecipeRequest) validate() []problem.FieldError {
+	if req.YieldInGrams != 0 {
+		return nil
+	}
+	var total float64
+	for _, ingredient := range req.Ingredients {
+		total += ingredient.AmountInGrams
+	}
+	if total > maxAmount {
+		return []problem.FieldError{{
+			Field:   "yield_in_grams",
+			Message: fmt.Sprintf("is required when the ingredients weigh more than %.2f g", maxAmount),
+		}}
+	}
+	return nil
+}
+
+func (req *RecipeRequest) toRecipe(recipeID int64) *store.Recipe {
+	recipe := &store.Recipe{RecipeID: recipeID, Name: req.Name, YieldInGrams: req.YieldInGrams}
+	var total float64
+	for _, ingredient := range req.Ingredients {
+		total += ingredient.AmountInGrams
+		recipe.Ingredients = append(recipe.Ingredients, store.RecipeIngredient{
+			IngredientID:  ingredient.IngredientID,
+			AmountInGrams: ingredient.AmountInGrams,
+		})
+	}
+	if recipe.YieldInGrams == 0 {
+		recipe.YieldInGrams = total
+	}
+	return recipe
+}
+
+// writeRecipeError writes the response for recipe write errors and reports
+// whether err was one.
+func writeRecipeError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, store.ErrRecipeNotFound):
+		problem.Write(w, errRecipeNotFound)
+	case errors.Is(err, store.ErrIngredientExists):
+		problem.Write(w, errIngredientExists)
+	case errors.Is(err, store.ErrIngredientNotFound):
+		problem.Write(w, errUnknownIngredient)
+	case errors.Is(err, store.ErrNestedRecipe):
+		problem.Write(w, errNestedRecipe)
+	case errors.Is(err, store.ErrRecipeIngredientExists):
+		problem.Write(w, errDuplicateRecipeIngredient)
+	default:
+		return false
+	}
+	return true
+}
+
+type CreateRecipeResponse struct {
+	RecipeID int64 `json:"recipe_id"`
+}
+
+// POST /api/recipes
+func (h *RecipeHandler) CreateRecipeHandle(w http.ResponseWriter, r *http.Request) {
+	var recipeRequest RecipeRequest
+	if !decodeRequest(w, r, &recipeRequest) {
+		return
+	}
+	if errs := recipeRequest.validate(); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	recipeID, err := h.recipes.CreateRecipe(r.Context(), recipeRequest.toRecipe(0))
+	if writeRecipeError(w, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while creating recipe", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&CreateRecipeResponse{RecipeID: recipeID})
+}
+
+type RecipeIngredientResponse struct {
+	IngredientID  int64   `json:"ingredient_id"`
+	Name          string  `json:"name"`
+	AmountInGrams float64 `json:"amount_in_grams"`
+}
+
+// RecipeResponse shows a recipe with its derived nutrients per 100 g of the
+// cooked dish. The recipe ID doubles as the ingredient ID used in meals.
+type RecipeResponse struct {
+	RecipeID     int64                      `json:"recipe_id"`
+	Name         string                     `json:"name"`
+	YieldInGrams float64                    `json:"yield_in_grams"`
+	UpdatedAt    time.Time                  `json:"updated_at"`
+	Ingredients  []RecipeIngredientResponse `json:"ingredients"`
+	Nutrients    []Nutrient                 `json:"nutrients"`
+}
+
+func newRecipeResponse(recipe *store.Recipe) *RecipeResponse {
+	response := &RecipeResponse{
+		RecipeID:     recipe.RecipeID,
+		Name:         recipe.Name,
+		YieldInGrams: recipe.YieldInGrams,
+		UpdatedAt:    recipe.UpdatedAt,
+		Ingredients:  []RecipeIngredientResponse{},
+		Nutrients:    []Nutrient{},
+	}
+	for _, ingredient := range recipe.Ingredients {
+		response.Ingredients = append(response.Ingredients, RecipeIngredientResponse{IngredientID: ingredient.IngredientID, Name: ingredient.Name, AmountInGrams: ingredient.AmountInGrams})
+	}
+	for _, nutrient := range recipe.Nutrients {
+		response.Nutrients = append(response.Nutrients, Nutrient{Name: nutrient.Name, Unit: nutrient.Unit, AmountPer100g: nutrient.AmountPer100g})
+	}
+	return response
+}
+
+func writeRecipe(w http.ResponseWriter, recipe *store.Recipe) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newRecipeResponse(recipe))
+}
+
+// GET /api/recipes/{id}
+func (h *RecipeHandler) GetRecipeHandle(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing recipeID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	recipe, err := h.recipes.GetRecipe(r.Context(), recipeID)
+	if errors.Is(err, store.ErrRecipeNotFound) {
+		problem.Write(w, errRecipeNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while getting recipe", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+	writeRecipe(w, recipe)
+}
+
+// PUT /api/recipes/{id}
+func (h *RecipeHandler) UpdateRecipeHandle(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing recipeID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	var recipeRequest RecipeRequest
+	if !decodeRequest(w, r, &recipeRequest) {
+		return
+	}
+	if errs := recipeRequest.validate(); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	replaced, err := h.recipes.ReplaceRecipe(r.Context(), recipeRequest.toRecipe(recipeID))
+	if writeRecipeError(w, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while replacing recipe", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+	writeRecipe(w, replaced)
+}
+
+// DELETE /api/recipes/{id}
+func (h *RecipeHandler) DeleteRecipeHandle(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := pathID(r, "id")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while parsing recipeID", "error", err)
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+
+	recipe, err := h.recipes.DeleteRecipe(r.Context(), recipeID)
+	if errors.Is(err, store.ErrRecipeNotFound) {
+		problem.Write(w, errRecipeNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while deleting recipe", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+	writeRecipe(w, recipe)
+}
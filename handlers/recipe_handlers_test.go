@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// createIngredientWith creates an ingredient with nutrient amounts per 100 g
+// through the API and returns its ID.
+func (s *testServer) createIngredientWith(t *testing.T, name string, nutrients map[string]float64) int64 {
+	t.Helper()
+	var lines []map[string]any
+	for nutrient, amount := range nutrients {
+		lines = append(lines, map[string]any{"name": nutrient, "amount": amount})
+	}
+	var created CreateIngredientResponse
+	decode(t, s.do(t, s.alice, "POST", "/api/ingredients", map[string]any{"name": name, "nutrients": lines}), http.StatusCreated, &created)
+	return created.IngredientID
+}
+
+func TestRecipeCRUD(t *testing.T) {
+	s := newTestServer(t)
+	rice := s.createIngredientWith(t, "Rice", map[string]float64{"Carbohydrate": 80, "Protein": 7})
+	oil := s.createIngredientWith(t, "Oil", map[string]float64{"Fat": 100})
+
+	// 100 g of rice and 10 g of oil cook to 250 g
+	var created CreateRecipeResponse
+	decode(t, s.do(t, s.alice, "POST", "/api/recipes", map[string]any{
+		"name":           "Fried rice",
+		"yield_in_grams": 250,
+		"ingredients": []map[string]any{
+			{"ingredient_id": rice, "amount_in_grams": 100},
+			{"ingredient_id": oil, "amount_in_grams": 10},
+		},
+	}), http.StatusCreated, &created)
+	path := fmt.Sprintf("/api/recipes/%d", created.RecipeID)
+
+	var recipe RecipeResponse
+	decode(t, s.do(t, s.alice, "GET", path, nil), http.StatusOK, &recipe)
+	want := []Nutrient{{Name: "Carbohydrate", Unit: "g", AmountPer100g: 32}, {Name: "Fat", Unit: "g", AmountPer100g: 4}, {Name: "Protein", Unit: "g", AmountPer100g: 2.8}}
+	if recipe.Name != "Fried rice" || recipe.YieldInGrams != 250 || len(recipe.Ingredients) != 2 || fmt.Sprint(recipe.Nutrients) != fmt.Sprint(want) {
+		t.Fatalf("GET recipe = %+v, want nutrients %+v", recipe, want)
+	}
+
+	// a meal eats the recipe like any other ingredient
+	mealID := s.createMeal(t, s.alice, map[string]any{
+		"name":        "Lunch",
+		"date_time":   "2023-11-20T12:00:00Z",
+		"ingredients": []map[string]any{{"ingredient_id": created.RecipeID, "amount_in_grams": 50}},
+	})
+	var nutrition MealNutritionResponse
+	decode(t, s.do(t, s.alice, "GET", fmt.Sprintf("/api/meals/%d/nutrition", mealID), nil), http.StatusOK, &nutrition)
+	totals := make(map[string]float64)
+	for _, total := range nutrition.Totals {
+		totals[total.Name] = total.Amount
+	}
+	if fmt.Sprint(totals) != fmt.Sprint(map[string]float64{"Carbohydrate": 16, "Fat": 2, "Protein": 1.4}) {
+		t.Errorf("nutrition of 50 g of the recipe = %v", totals)
+	}
+
+	// without a yield the raw weight of the ingredients is used
+	decode(t, s.do(t, s.alice, "PUT", path, map[string]any{
+		"name":        "Plain rice",
+		"ingredients": []map[string]any{{"ingredient_id": rice, "amount_in_grams": 200}},
+	}), http.StatusOK, &recipe)
+	want = []Nutrient{{Name: "Carbohydrate", Unit: "g", AmountPer100g: 80}, {Name: "Protein", Unit: "g", AmountPer100g: 7}}
+	if recipe.Name != "Plain rice" || recipe.YieldInGrams != 200 || fmt.Sprint(recipe.Nutrients) != fmt.Sprint(want) {
+		t.Errorf("PUT recipe = %+v, want nutrients %+v", recipe, want)
+	}
+
+	ingredientPath := fmt.Sprintf("/api/ingredients/%d", created.RecipeID)
+	expectProblem(t, s.do(t, s.alice, "PUT", ingredientPath, map[string]any{"name": "Plain rice", "nutrients": []any{}}), http.StatusConflict, "ingredient_is_recipe")
+	expectProblem(t, s.do(t, s.alice, "DELETE", fmt.Sprintf("/api/ingredients/%d", rice), nil), http.StatusConflict, "ingredient_in_use")
+
+	decode(t, s.do(t, s.alice, "DELETE", path, nil), http.StatusOK, nil)
+	expectProblem(t, s.do(t, s.alice, "GET", path, nil), http.StatusNotFound, "recipe_not_found")
+}
+
+func TestRecipeErrors(t *testing.T) {
+	s := newTestServer(t)
+	rice := s.createIngredient(t, s.alice, "Rice")
+	oats := s.createIngredient(t, s.alice, "Oats")
+	var created CreateRecipeResponse
+	decode(t, s.do(t, s.alice, "POST", "/api/recipes", map[string]any{
+		"name":        "Boiled rice",
+		"ingredients": []map[string]any{{"ingredient_id": rice, "amount_in_grams": 100}},
+	}), http.StatusCreated, &created)
+
+	recipe := func(name string, lines ...map[string]any) map[string]any {
+		return map[string]any{"name": name, "ingredients": lines}
+	}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"unknown recipe", "GET", "/api/recipes/999", nil, http.StatusNotFound, "recipe_not_found"},
+		{"replace unknown recipe", "PUT", "/api/recipes/999", recipe("Nothing", map[string]any{"ingredient_id": rice, "amount_in_grams": 1}), http.StatusNotFound, "recipe_not_found"},
+		{"no ingredients", "POST", "/api/recipes", recipe("Air"), http.StatusUnprocessableEntity, "validation_failed"},
+		{"taken name", "POST", "/api/recipes", recipe("Rice", map[string]any{"ingredient_id": rice, "amount_in_grams": 100}), http.StatusConflict, "ingredient_exists"},
+		{"unknown ingredient", "POST", "/api/recipes", recipe("Mystery", map[string]any{"ingredient_id": 999, "amount_in_grams": 100}), http.StatusUnprocessableEntity, "unknown_ingredient"},
+		{"nested recipe", "POST", "/api/recipes", recipe("Rice bowl", map[string]any{"ingredient_id": created.RecipeID, "amount_in_grams": 100}), http.StatusUnprocessableEntity, "nested_recipe"},
+		{
+			"duplicate ingredient", "POST", "/api/recipes",
+			recipe("Double rice", map[string]any{"ingredient_id": rice, "amount_in_grams": 100}, map[string]any{"ingredient_id": rice, "amount_in_grams": 50}),
+			http.StatusBadRequest, "duplicate_recipe_ingredient",
+		},
+		{
+			"derived yield too large", "POST", "/api/recipes",
+			recipe("Silo", map[string]any{"ingredient_id": rice, "amount_in_grams": 99999999}, map[string]any{"ingredient_id": oats, "amount_in_grams": 99999999}),
+			http.StatusUnprocessableEntity, "validation_failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectProblem(t, s.do(t, s.alice, test.method, test.path, test.body), test.status, test.code)
+		})
+	}
+}
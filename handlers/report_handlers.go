@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"assignment2/problem"
+	"assignment2/reports"
+	"assignment2/store"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type ReportHandler struct {
+	meals store.MealStore
+}
+
+func NewReportHandler(meals store.MealStore) *ReportHandler {
+	return &ReportHandler{meals: meals}
+}
+
+type ReportMealResponse struct {
+	MealID int64                   `json:"meal_id"`
+	Name   string                  `json:"name"`
+	Date   time.Time               `json:"date"`
+	Time   time.Time               `json:"time"`
+	Totals []NutrientTotalResponse `json:"totals"`
+}
+
+type ReportDayResponse struct {
+	Date   string                  `json:"date"`
+	Totals []NutrientTotalResponse `json:"totals"`
+}
+
+type ReportResponse struct {
+	From          string                  `json:"from"`
+	To            string                  `json:"to"`
+	Days          int                     `json:"days"`
+	DaysWithMeals int                     `json:"days_with_meals"`
+	Totals        []NutrientTotalResponse `json:"totals"`
+	AveragePerDay []NutrientTotalResponse `json:"average_per_day"`
+	PerDay        []ReportDayResponse     `json:"per_day"`
+	Meals         []ReportMealResponse    `json:"meals"`
+}
+
+func newReportResponse(report *reports.Report) *ReportResponse {
+	response := &ReportResponse{
+		From:          report.From.Format(time.DateOnly),
+		To:            report.To.Format(time.DateOnly),
+		Days:          report.Days,
+		DaysWithMeals: report.DaysWithMeals,
+		Totals:        newNutrientTotalsResponse(report.Totals),
+		AveragePerDay: newNutrientTotalsResponse(report.AveragePerDay),
+		PerDay:        []ReportDayResponse{},
+		Meals:         []ReportMealResponse{},
+	}
+	for _, day := range report.PerDay {
+		response.PerDay = append(response.PerDay, ReportDayResponse{
+			Date:   day.Date.Format(time.DateOnly),
+			Totals: newNutrientTotalsResponse(day.Totals),
+		})
+	}
+	for _, meal := range report.Meals {
+		response.Meals = append(response.Meals, ReportMealResponse{
+			MealID: meal.MealID,
+			Name:   meal.Name,
+			Date:   meal.Date,
+			Time:   meal.Time,
+			Totals: newNutrientTotalsResponse(meal.Totals),
+		})
+	}
+	return response
+}
+
+func writeReport(w http.ResponseWriter, report *reports.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newReportResponse(report))
+}
+
+// GET /api/reports/daily?date=YYYY-MM-DD, defaulting to today (UTC)
+func (h *ReportHandler) DailyReportHandle(w http.ResponseWriter, r *http.Request) {
+	date, err := queryDate(r, "date")
+	if err != nil {
+		problem.Write(w, errInvalidParameter.With(err.Error()))
+		return
+	}
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+
+	report, err := reports.Daily(r.Context(), h.meals, currentUserID(r), date)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while building daily report", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+	writeReport(w, report)
+}
+
+// GET /api/reports/weekly?week=YYYY-Www, defaulting to the current ISO week (UTC)
+func (h *ReportHandler) WeeklyReportHandle(w http.ResponseWriter, r *http.Request) {
+	monday := reports.StartOfISOWeek(time.Now().UTC())
+	if week := r.URL.Query().Get("week"); week != "" {
+		var err error
+		monday, err = reports.ParseISOWeek(week)
+		if err != nil {
+			problem.Write(w, errInvalidParameter.With(err.Error()))
+			return
+		}
+	}
+
+	report, err := reports.Weekly(r.Context(), h.meals, currentUserID(r), monday)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while building weekly report", "error", err)
+		writeServerError(w, r, err)
+		return
+	}
+	writeReport(w, report)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"assignment2/auth"
+	"assignment2/barcode"
+	"assignment2/problem"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate enforces the validate tags of the request structs. Besides the
+// validator's built in tags it knows "notblank", which rejects whitespace
+// only strings, "gtin", which accepts anything barcode.Normalize does, and
+// "password", which applies the length limits of the auth package.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// report fields under the names clients send
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	v.RegisterValidation("gtin", func(fl validator.FieldLevel) bool {
+		_, err := barcode.Normalize(fl.Field().String())
+		return err == nil
+	})
+	v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		password := fl.Field().String()
+		return utf8.RuneCountInString(password) >= auth.MinPasswordLength && len(password) <= auth.MaxPasswordLength
+	})
+	return v
+}
+
+// maxAmount is the largest value of the NUMERIC(10,2) columns. Request
+// fields stored in them are limited by "lte=99999999.99" tags; amounts the
+// handlers compute from those fields are checked against it as well.
+const maxAmount = 99999999.99
+
+// decodeRequest decodes the JSON request body into dst, which must be a
+// pointer to a struct, and validates it against its validate tags. It answers
+// malformed bodies with 400 and invalid fields with 422, and reports whether
+// the handler may go on.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst any) bool {
+	err := json.NewDecoder(r.Body).Decode(dst)
+	if errors.Is(err, io.EOF) {
+		problem.Write(w, errEmptyBody)
+		return false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while decoding request body", "error", err)
+		problem.Write(w, errInvalidJSON.With(err.Error()))
+		return false
+	}
+
+	err = validate.Struct(dst)
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		writeValidationErrors(w, fieldErrors(invalid))
+		return false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while validating request body", "error", err)
+		writeServerError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// writeValidationErrors answers with a validation_failed problem listing errs.
+func writeValidationErrors(w http.ResponseWriter, errs []problem.FieldError) {
+	problem.Write(w, validationProblem(errs))
+}
+
+func validationProblem(errs []problem.FieldError) *problem.Problem {
+	invalid := *errValidationFailed
+	invalid.Errors = errs
+	return &invalid
+}
+
+func fieldErrors(invalid validator.ValidationErrors) []problem.FieldError {
+	errs := make([]problem.FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		// drop the struct name that starts every namespace
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		errs = append(errs, problem.FieldError{Field: field, Message: fieldMessage(fe)})
+	}
+	return errs
+}
+
+// fieldMessage explains a failed tag in words. The tags are the ones used by
+// the request structs of this package.
+func fieldMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "email":
+		return "must be a valid email address"
+	case "gtin":
+		return barcode.ErrInvalid.Error()
+	case "password":
+		return fmt.Sprintf("must be at least %d characters and at most %d bytes long", auth.MinPasswordLength, auth.MaxPasswordLength)
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must not be less than " + fe.Param()
+	case "lte":
+		return "must not be greater than " + fe.Param()
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		if fe.Param() == "1" {
+			return "must not be empty"
+		}
+		return fmt.Sprintf("must contain at least %s items", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must contain at most %s items", fe.Param())
+	}
+	return fmt.Sprintf("failed the %q check", fe.Tag())
+}
@@ -0,0 +1,204 @@
+// Package health serves the liveness and readiness probes, GET /health and
+// GET /ready. Both report the state of the database and the build; /ready
+// additionally fails unless the database answers and its schema is current.
+package health
+
+import (
+	"assignment2/migrations"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds the database checks of a single probe.
+const checkTimeout = 2 * time.Second
+
+// The errors of the database checks. The probes are not authenticated, so
+// the report only says which check failed and the error itself is logged.
+const (
+	databaseUnreachable = "database unreachable"
+	schemaCheckFailed   = "schema check failed"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDraining = "draining"
+)
+
+type Checker struct {
+	db       *sql.DB
+	migrator *migrations.Migrator
+	started  time.Time
+	draining atomic.Bool
+	build    BuildInfo
+}
+
+// New returns a checker for db, which is nil when the server runs on the
+// memory store.
+func New(db *sql.DB) (*Checker, error) {
+	c := &Checker{db: db, started: time.Now(), build: readBuildInfo()}
+	if db != nil {
+		migrator, err := migrations.New(db)
+		if err != nil {
+			return nil, err
+		}
+		c.migrator = migrator
+	}
+	return c, nil
+}
+
+// Drain makes both probes fail from now on, so that load balancers stop
+// sending traffic while the server shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+type Report struct {
+	Status        string          `json:"status"`
+	UptimeSeconds float64         `json:"uptime_seconds"`
+	Storage       string          `json:"storage"`
+	Database      *DatabaseReport `json:"database,omitempty"`
+	Build         BuildInfo       `json:"build"`
+}
+
+type DatabaseReport struct {
+	Up                  bool       `json:"up"`
+	Error               string     `json:"error,omitempty"`
+	PingLatencyMs       float64    `json:"ping_latency_ms"`
+	SchemaVersion       int        `json:"schema_version"`
+	LatestSchemaVersion int        `json:"latest_schema_version"`
+	Pool                PoolReport `json:"pool"`
+}
+
+// PoolReport is the sql.DBStats of the pool. Saturation is the share of the
+// maximum number of connections in use, 0 when the pool is unbounded.
+type PoolReport struct {
+	MaxOpen        int     `json:"max_open"`
+	Open           int     `json:"open"`
+	InUse          int     `json:"in_use"`
+	Idle           int     `json:"idle"`
+	Saturation     float64 `json:"saturation"`
+	WaitCount      int64   `json:"wait_count"`
+	WaitDurationMs float64 `json:"wait_duration_ms"`
+}
+
+type BuildInfo struct {
+	GoVersion string `json:"go_version"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+func readBuildInfo() BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{Version: "unknown"}
+	}
+	build := BuildInfo{GoVersion: info.GoVersion, Version: info.Main.Version}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.time":
+			build.Time = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
+
+// Check runs the checks and sums them up in the report's status: ok,
+// degraded when the database is down or its schema is behind, or draining.
+func (c *Checker) Check(ctx context.Context) *Report {
+	report := &Report{
+		Status:        StatusOK,
+		UptimeSeconds: time.Since(c.started).Round(time.Second).Seconds(),
+		Storage:       "memory",
+		Build:         c.build,
+	}
+	if c.db != nil {
+		report.Storage = "postgres"
+		report.Database = c.checkDatabase(ctx)
+		if !report.Database.Up || report.Database.SchemaVersion < report.Database.LatestSchemaVersion {
+			report.Status = StatusDegraded
+		}
+	}
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) *DatabaseReport {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	report := &DatabaseReport{LatestSchemaVersion: c.migrator.Latest()}
+	stats := c.db.Stats()
+	report.Pool = PoolReport{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDurationMs: float64(stats.WaitDuration.Microseconds()) / 1000,
+	}
+	if stats.MaxOpenConnections > 0 {
+		report.Pool.Saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+
+	start := time.Now()
+	err := c.db.PingContext(ctx)
+	report.PingLatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		slog.WarnContext(ctx, "Health check could not reach the database", "error", err)
+		report.Error = databaseUnreachable
+		return report
+	}
+	report.Up = true
+
+	version, err := c.migrator.Version(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Health check could not read the schema version", "error", err)
+		report.Error = schemaCheckFailed
+		return report
+	}
+	report.SchemaVersion = version
+	return report
+}
+
+// GET /health is the liveness probe. It fails only while draining; a
+// degraded database is reported but does not make the server unhealthy.
+func (c *Checker) HealthHandle(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if report.Status == StatusDraining {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+// GET /ready is the readiness probe. It succeeds only when every check does.
+func (c *Checker) ReadyHandle(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+func writeReport(w http.ResponseWriter, status int, report *Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
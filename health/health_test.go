@@ -0,0 +1,88 @@
+package health
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// unreachable is a driver whose connections always fail with err.
+type unreachable struct{ err error }
+
+func (d unreachable) Open(string) (driver.Conn, error) { return nil, d.err }
+
+func init() {
+	sql.Register("health-unreachable", unreachable{errors.New(`dial tcp 10.1.2.3:5432: password authentication failed for user "nutrition"`)})
+}
+
+// probe calls a probe handler and returns its status, body and report.
+func probe(t *testing.T, handle http.HandlerFunc) (int, string, *Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handle(rec, httptest.NewRequest("GET", "/", nil))
+	body := rec.Body.String()
+	var report Report
+	if err := json.Unmarshal([]byte(body), &report); err != nil {
+		t.Fatalf("decoding report: %v", err)
+	}
+	return rec.Code, body, &report
+}
+
+func TestProbesOnMemoryStore(t *testing.T) {
+	c, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for name, handle := range map[string]http.HandlerFunc{"health": c.HealthHandle, "ready": c.ReadyHandle} {
+		status, _, report := probe(t, handle)
+		if status != http.StatusOK || report.Status != StatusOK || report.Storage != "memory" || report.Database != nil {
+			t.Errorf("%s = %d %+v", name, status, report)
+		}
+	}
+
+	c.Drain()
+	for name, handle := range map[string]http.HandlerFunc{"health": c.HealthHandle, "ready": c.ReadyHandle} {
+		if status, _, report := probe(t, handle); status != http.StatusServiceUnavailable || report.Status != StatusDraining {
+			t.Errorf("%s while draining = %d %+v", name, status, report)
+		}
+	}
+}
+
+func TestProbesWithUnreachableDatabase(t *testing.T) {
+	db, err := sql.Open("health-unreachable", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c, err := New(db)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// the database is reported but only fails readiness
+	tests := []struct {
+		name   string
+		handle http.HandlerFunc
+		want   int
+	}{
+		{"health", c.HealthHandle, http.StatusOK},
+		{"ready", c.ReadyHandle, http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		status, body, report := probe(t, test.handle)
+		if status != test.want || report.Status != StatusDegraded {
+			t.Errorf("%s = %d %q, want %d %q", test.name, status, report.Status, test.want, StatusDegraded)
+		}
+		if report.Database == nil || report.Database.Up || report.Database.Error != databaseUnreachable {
+			t.Errorf("%s database = %+v", test.name, report.Database)
+		}
+		if strings.Contains(body, "10.1.2.3") || strings.Contains(body, "nutrition") {
+			t.Errorf("%s leaks the database error: %s", test.name, body)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"assignment2/catalog"
+	"assignment2/config"
+	"assignment2/importer"
+	"assignment2/store"
+	"context"
+	"flag"
+	"log"
+	"os"
+)
+
+const importUsage = "usage: myhttpserver import [-source fdc|off] [-batch N] [-config FILE] [setting flags] PATH"
+
+// runImport implements the import subcommand, which loads a local food
+// composition dump straight into Postgres.
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	source := flags.String("source", importer.SourceFDC, "format of the dump")
+	batchSize := flags.Int("batch", importer.DefaultBatchSize, "ingredients written per transaction")
+	loadConfig := config.AddFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal(importUsage)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db := initDB(cfg.Database)
+	defer db.Close()
+	st := store.NewPostgres(db)
+
+	ctx := context.Background()
+	if err := st.SyncNutrientCatalog(ctx, catalog.Nutrients); err != nil {
+		log.Fatalf("Syncing the nutrient catalog: %v", err)
+	}
+
+	summary, err := importer.Import(ctx, st, *source, flags.Arg(0), *batchSize)
+	if summary != nil {
+		log.Printf("Read %d foods: %d created, %d updated, %d unchanged, %d skipped\n", summary.Read, summary.Created, summary.Updated, summary.Unchanged, summary.Skipped)
+	}
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readCSV calls fn with the named columns of every row of a CSV file with a
+// header line.
+func readCSV(path string, columns []string, fn func(row []string) error) error {
+	return readTable(path, ',', columns, len(columns), fn)
+}
+
+// readTable is readCSV for any separator. Only the first required columns
+// must be in the header, the others are passed to fn as empty strings when
+// missing. Tab separated files are read with lazy quotes since their fields
+// are not quoted.
+func readTable(path string, comma rune, columns []string, required int, fn func(row []string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.Comma = comma
+	r.LazyQuotes = comma == '\t'
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	index := make([]int, len(columns))
+	for i, column := range columns {
+		index[i] = -1
+		for j, name := range header {
+			if strings.TrimPrefix(name, "\ufeff") == column {
+				index[i] = j
+			}
+		}
+		if index[i] < 0 && i < required {
+			return fmt.Errorf("%s: missing column %q", path, column)
+		}
+	}
+
+	row := make([]string, len(columns))
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for i, j := range index {
+			if j >= 0 && j < len(record) {
+				row[i] = record[j]
+			} else {
+				row[i] = ""
+			}
+		}
+		if err := fn(row); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+	}
+}
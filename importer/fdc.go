@@ -0,0 +1,260 @@
+package importer
+
+import (
+	"assignment2/catalog"
+	"assignment2/store"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fdcNutrients lists the FoodData Central nutrient IDs of each catalog
+// nutrient, preferred ID first. Energy for example is reported as 1008 in SR
+// Legacy but only as the Atwater factors 2047 and 2048 for many Foundation
+// foods.
+var fdcNutrients = map[string][]int{
+	"Energy":        {1008, 2047, 2048},
+	"Protein":       {1003},
+	"Carbohydrate":  {1005, 1050},
+	"Sugars":        {2000, 1063},
+	"Fiber":         {1079},
+	"Fat":           {1004, 1085},
+	"Saturated Fat": {1258},
+	"Vitamin A":     {1106},
+	"Thiamin":       {1165},
+	"Riboflavin":    {1166},
+	"Niacin":        {1167},
+	"Vitamin B6":    {1175},
+	"Folate":        {1177, 1190},
+	"Vitamin B12":   {1178},
+	"Vitamin C":     {1162},
+	"Vitamin D":     {1114},
+	"Vitamin E":     {1109},
+	"Vitamin K":     {1185},
+	"Calcium":       {1087},
+	"Iron":          {1089},
+	"Magnesium":     {1090},
+	"Phosphorus":    {1091},
+	"Potassium":     {1092},
+	"Sodium":        {1093},
+	"Zinc":          {1095},
+	"Selenium":      {1103},
+}
+
+type fdcMapping struct {
+	nutrient catalog.Nutrient
+	rank     int
+}
+
+var byFDCID = make(map[int]fdcMapping)
+
+func init() {
+	for name, ids := range fdcNutrients {
+		nutrient, ok := catalog.Lookup(name)
+		if !ok {
+			panic("importer: " + name + " is not in the nutrient catalog")
+		}
+		for rank, id := range ids {
+			byFDCID[id] = fdcMapping{nutrient: nutrient, rank: rank}
+		}
+	}
+}
+
+// fdcFood collects the catalog nutrients of one food, keeping the preferred
+// FDC nutrient when several map to the same catalog nutrient.
+type fdcFood struct {
+	name   string
+	values map[string]fdcValue
+}
+
+type fdcValue struct {
+	rank   int
+	amount float64
+}
+
+func newFDCFood(name string) *fdcFood {
+	return &fdcFood{name: strings.TrimSpace(name), values: make(map[string]fdcValue)}
+}
+
+// add records amount, given per 100 g in unit, of the FDC nutrient id.
+// Nutrients outside the catalog or in units that cannot be converted are
+// ignored.
+func (f *fdcFood) add(id int, unit string, amount float64) {
+	mapping, ok := byFDCID[id]
+	if !ok || amount < 0 {
+		return
+	}
+	parsed, ok := catalog.ParseUnit(unit)
+	if !ok {
+		return
+	}
+	converted, ok := catalog.Convert(amount, parsed, mapping.nutrient.Unit)
+	if !ok {
+		return
+	}
+	if existing, ok := f.values[mapping.nutrient.Name]; ok && existing.rank <= mapping.rank {
+		return
+	}
+	f.values[mapping.nutrient.Name] = fdcValue{rank: mapping.rank, amount: converted}
+}
+
+func (f *fdcFood) food() Food {
+	food := Food{Name: f.name}
+	for name, value := range f.values {
+		food.Nutrients = append(food.Nutrients, store.NutrientValue{Name: name, AmountPer100g: value.amount})
+	}
+	sort.Slice(food.Nutrients, func(i, j int) bool {
+		return food.Nutrients[i].Name < food.Nutrients[j].Name
+	})
+	return food
+}
+
+// readFDC reads either a FoodData Central JSON download, such as the
+// Foundation or SR Legacy JSON file, or a directory holding the CSV download
+// with its food.csv, nutrient.csv and food_nutrient.csv files.
+func readFDC(path string, fn func(Food) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return readFDCCSV(path, fn)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return readFDCJSON(path, fn)
+	}
+	return fmt.Errorf("%s: expected a .json file or a directory of CSV files", path)
+}
+
+type fdcJSONFood struct {
+	Description   string `json:"description"`
+	FoodNutrients []struct {
+		Nutrient struct {
+			ID       int    `json:"id"`
+			UnitName string `json:"unitName"`
+		} `json:"nutrient"`
+		Amount *float64 `json:"amount"`
+	} `json:"foodNutrients"`
+}
+
+// readFDCJSON streams the foods of the top level array, named after the data
+// type such as "FoundationFoods", one at a time.
+func readFDCJSON(path string, fn func(Food) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	if err := expectDelim(dec, '{'); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := expectDelim(dec, '['); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for dec.More() {
+			var item fdcJSONFood
+			if err := dec.Decode(&item); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			food := newFDCFood(item.Description)
+			for _, nutrient := range item.FoodNutrients {
+				if nutrient.Amount != nil {
+					food.add(nutrient.Nutrient.ID, nutrient.Nutrient.UnitName, *nutrient.Amount)
+				}
+			}
+			if err := fn(food.food()); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, found %v", delim, token)
+	}
+	return nil
+}
+
+// readFDCCSV joins the CSV files in memory. Only the per food nutrient
+// amounts are kept, which is small even for SR Legacy.
+func readFDCCSV(dir string, fn func(Food) error) error {
+	units := make(map[int]string)
+	err := readCSV(filepath.Join(dir, "nutrient.csv"), []string{"id", "unit_name"}, func(row []string) error {
+		id, err := strconv.Atoi(row[0])
+		if err != nil {
+			return err
+		}
+		units[id] = row[1]
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	foods := make(map[int]*fdcFood)
+	var order []int
+	err = readCSV(filepath.Join(dir, "food.csv"), []string{"fdc_id", "description"}, func(row []string) error {
+		id, err := strconv.Atoi(row[0])
+		if err != nil {
+			return err
+		}
+		if _, ok := foods[id]; !ok {
+			order = append(order, id)
+		}
+		foods[id] = newFDCFood(row[1])
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = readCSV(filepath.Join(dir, "food_nutrient.csv"), []string{"fdc_id", "nutrient_id", "amount"}, func(row []string) error {
+		fdcID, err := strconv.Atoi(row[0])
+		if err != nil {
+			return err
+		}
+		food, ok := foods[fdcID]
+		if !ok || row[2] == "" {
+			return nil
+		}
+		nutrientID, err := strconv.Atoi(row[1])
+		if err != nil {
+			return err
+		}
+		amount, err := strconv.ParseFloat(row[2], 64)
+		if err != nil {
+			return err
+		}
+		food.add(nutrientID, units[nutrientID], amount)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range order {
+		if err := fn(foods[id].food()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
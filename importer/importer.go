@@ -0,0 +1,117 @@
+// Package importer loads ingredients in bulk from locally stored food
+// composition dumps. Each source format has a reader that turns the dump into
+// Foods with catalog nutrient names and amounts per 100 g, which Import then
+// upserts in batches.
+package importer
+
+import (
+	"assignment2/store"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+const DefaultBatchSize = 500
+
+// maxNameLength is the size of the Ingredients.Name column.
+const maxNameLength = 255
+
+// Sources accepted by Import.
+const (
+	SourceFDC = "fdc"
+	SourceOFF = "off"
+)
+
+var ErrUnknownSource = errors.New("unknown import source")
+
+// ErrInvalidDump wraps the errors of dumps that cannot be parsed. Errors
+// opening the dump or writing its foods are returned as they are.
+var ErrInvalidDump = errors.New("invalid dump")
+
+// Food is one food read from a dump. Nutrients only hold catalog nutrients,
+// amounts are per 100 g. Packaged products also carry their normalised
+// barcode and brand.
+type Food struct {
+	Name      string
+	Barcode   string
+	Brand     string
+	Nutrients []store.NutrientValue
+}
+
+// Summary counts what happened to the foods of a dump. Skipped covers foods
+// without a usable name, barcode or catalog nutrient as well as those whose
+// name belongs to a recipe or, for products, to another ingredient.
+type Summary struct {
+	Read      int
+	Created   int
+	Updated   int
+	Unchanged int
+	Skipped   int
+}
+
+// reader reads the dump at path and calls fn for every food in it.
+type reader func(path string, fn func(Food) error) error
+
+var readers = map[string]reader{
+	SourceFDC: readFDC,
+	SourceOFF: readOFF,
+}
+
+// Import reads the dump at path in the given source format and upserts its
+// foods as ingredients, batchSize at a time. Batches that were written stay
+// written when a later one fails.
+func Import(ctx context.Context, ingredients store.IngredientStore, source string, path string, batchSize int) (*Summary, error) {
+	read, ok := readers[source]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSource, source)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	summary := &Summary{}
+	batch := make([]store.Ingredient, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		upserted, err := ingredients.UpsertIngredients(ctx, batch)
+		if err != nil {
+			return err
+		}
+		summary.Created += upserted.Created
+		summary.Updated += upserted.Updated
+		summary.Unchanged += upserted.Unchanged
+		summary.Skipped += upserted.Skipped
+		batch = batch[:0]
+		return nil
+	}
+
+	// writeErr tells the errors of fn apart from those of the reader
+	var writeErr error
+	err := read(path, func(food Food) error {
+		summary.Read++
+		if food.Name == "" || len(food.Name) > maxNameLength || len(food.Brand) > maxNameLength || len(food.Nutrients) == 0 {
+			summary.Skipped++
+			return nil
+		}
+		batch = append(batch, store.Ingredient{Name: food.Name, Barcode: food.Barcode, Brand: food.Brand, Nutrients: food.Nutrients})
+		if len(batch) < batchSize {
+			return nil
+		}
+		if writeErr = ctx.Err(); writeErr != nil {
+			return writeErr
+		}
+		writeErr = flush()
+		return writeErr
+	})
+	var pathErr *fs.PathError
+	if err != nil && writeErr == nil && !errors.As(err, &pathErr) {
+		err = fmt.Errorf("%w: %w", ErrInvalidDump, err)
+	}
+	if err != nil {
+		return summary, err
+	}
+	return summary, flush()
+}
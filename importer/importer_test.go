@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"assignment2/catalog"
+	"assignment2/store"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingStore fails every upsert with err.
+type failingStore struct {
+	*store.Memory
+	err error
+}
+
+func (s failingStore) UpsertIngredients(context.Context, []store.Ingredient) (*store.UpsertSummary, error) {
+	return nil, s.err
+}
+
+func TestImportErrors(t *testing.T) {
+	ctx := context.Background()
+	ingredients := store.NewMemory()
+	if err := ingredients.SyncNutrientCatalog(ctx, catalog.Nutrients); err != nil {
+		t.Fatalf("SyncNutrientCatalog: %v", err)
+	}
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "products.jsonl")
+	if err := os.WriteFile(valid, []byte(offJSONL), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	malformed := filepath.Join(dir, "malformed.jsonl")
+	if err := os.WriteFile(malformed, []byte(`{"code": "4006381333931", "product_name": `), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	errStore := errors.New("connection reset")
+
+	tests := []struct {
+		name        string
+		ingredients store.IngredientStore
+		path        string
+		want        error
+		invalid     bool
+	}{
+		{"malformed dump", ingredients, malformed, nil, true},
+		{"missing dump", ingredients, filepath.Join(dir, "missing.jsonl"), fs.ErrNotExist, false},
+		{"failing store", failingStore{ingredients, errStore}, valid, errStore, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Import(ctx, test.ingredients, SourceOFF, test.path, 1)
+			if err == nil {
+				t.Fatal("Import succeeded")
+			}
+			if errors.Is(err, ErrInvalidDump) != test.invalid {
+				t.Errorf("Import error %q: errors.Is(ErrInvalidDump) = %v, want %v", err, !test.invalid, test.invalid)
+			}
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("Import error %q does not wrap %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"assignment2/barcode"
+	"assignment2/catalog"
+	"assignment2/store"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// offNutrients maps the Open Food Facts nutriment keys to catalog nutrients.
+// Open Food Facts stores every amount in grams except energy-kcal.
+var offNutrients = map[string]string{
+	"energy-kcal":   "Energy",
+	"proteins":      "Protein",
+	"carbohydrates": "Carbohydrate",
+	"sugars":        "Sugars",
+	"fiber":         "Fiber",
+	"fat":           "Fat",
+	"saturated-fat": "Saturated Fat",
+	"vitamin-a":     "Vitamin A",
+	"vitamin-b1":    "Thiamin",
+	"vitamin-b2":    "Riboflavin",
+	"vitamin-pp":    "Niacin",
+	"vitamin-b6":    "Vitamin B6",
+	"vitamin-b9":    "Folate",
+	"vitamin-b12":   "Vitamin B12",
+	"vitamin-c":     "Vitamin C",
+	"vitamin-d":     "Vitamin D",
+	"vitamin-e":     "Vitamin E",
+	"vitamin-k":     "Vitamin K",
+	"calcium":       "Calcium",
+	"iron":          "Iron",
+	"magnesium":     "Magnesium",
+	"phosphorus":    "Phosphorus",
+	"potassium":     "Potassium",
+	"sodium":        "Sodium",
+	"zinc":          "Zinc",
+	"selenium":      "Selenium",
+}
+
+// offKeys holds the keys of offNutrients in a fixed order, which the CSV
+// reader uses for its column list.
+var offKeys []string
+
+func init() {
+	for key, name := range offNutrients {
+		if _, ok := catalog.Lookup(name); !ok {
+			panic("importer: " + name + " is not in the nutrient catalog")
+		}
+		offKeys = append(offKeys, key)
+	}
+	sort.Strings(offKeys)
+}
+
+// offProduct is one product with its label values per 100 g and per serving.
+type offProduct struct {
+	code       string
+	name       string
+	brands     string
+	serving    float64
+	per100g    map[string]float64
+	perServing map[string]float64
+}
+
+func newOFFProduct() *offProduct {
+	return &offProduct{per100g: make(map[string]float64), perServing: make(map[string]float64)}
+}
+
+// set records a nutriment such as "proteins_100g" or "proteins_serving".
+// Other keys and values that are not non-negative numbers are ignored.
+func (p *offProduct) set(key string, value string) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || amount < 0 {
+		return
+	}
+	if nutrient, ok := strings.CutSuffix(key, "_100g"); ok {
+		p.per100g[nutrient] = amount
+	} else if nutrient, ok := strings.CutSuffix(key, "_serving"); ok {
+		p.perServing[nutrient] = amount
+	}
+}
+
+// food converts the product, preferring the values per 100 g and falling back
+// to the label values per serving. Products without a valid barcode come back
+// without a name so that Import skips them.
+func (p *offProduct) food() Food {
+	code, err := barcode.Normalize(p.code)
+	if err != nil {
+		return Food{}
+	}
+	food := Food{Name: strings.TrimSpace(p.name), Barcode: code}
+	// brands is a comma separated list, the first one is the owner
+	brand, _, _ := strings.Cut(p.brands, ",")
+	food.Brand = strings.TrimSpace(brand)
+
+	for _, key := range offKeys {
+		nutrient, _ := catalog.Lookup(offNutrients[key])
+		amount, ok := p.per100g[key]
+		if !ok {
+			perServing, found := p.perServing[key]
+			if !found || p.serving <= 0 {
+				continue
+			}
+			amount = catalog.ConvertToPerHundredGrams(perServing, p.serving)
+		}
+		if nutrient.Unit != catalog.UnitKcal {
+			amount, _ = catalog.Convert(amount, catalog.UnitGram, nutrient.Unit)
+		}
+		food.Nutrients = append(food.Nutrients, store.NutrientValue{Name: nutrient.Name, AmountPer100g: amount})
+	}
+	sort.Slice(food.Nutrients, func(i, j int) bool {
+		return food.Nutrients[i].Name < food.Nutrients[j].Name
+	})
+	return food
+}
+
+// readOFF reads an Open Food Facts dump: either the JSONL export with one
+// product per line or the CSV export, which is tab separated despite its
+// name.
+func readOFF(path string, fn func(Food) error) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return readOFFJSONL(path, fn)
+	case ".csv", ".tsv":
+		return readOFFCSV(path, fn)
+	}
+	return fmt.Errorf("%s: expected a .jsonl or .csv file", path)
+}
+
+type offJSONProduct struct {
+	Code            string                     `json:"code"`
+	ProductName     string                     `json:"product_name"`
+	Brands          string                     `json:"brands"`
+	ServingQuantity json.RawMessage            `json:"serving_quantity"`
+	Nutriments      map[string]json.RawMessage `json:"nutriments"`
+}
+
+func readOFFJSONL(path string, fn func(Food) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(file))
+	for line := 1; ; line++ {
+		var item offJSONProduct
+		err := dec.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s product %d: %w", path, line, err)
+		}
+
+		product := newOFFProduct()
+		product.code = item.Code
+		product.name = item.ProductName
+		product.brands = item.Brands
+		product.serving, _ = strconv.ParseFloat(jsonNumber(item.ServingQuantity), 64)
+		for key, value := range item.Nutriments {
+			product.set(key, jsonNumber(value))
+		}
+		if err := fn(product.food()); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonNumber returns the number in raw, which Open Food Facts writes either as
+// a JSON number or as a string.
+func jsonNumber(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func readOFFCSV(path string, fn func(Food) error) error {
+	comma, err := sniffComma(path)
+	if err != nil {
+		return err
+	}
+
+	columns := []string{"code", "product_name", "brands", "serving_quantity"}
+	required := len(columns)
+	for _, key := range offKeys {
+		columns = append(columns, key+"_100g", key+"_serving")
+	}
+	return readTable(path, comma, columns, required, func(row []string) error {
+		product := newOFFProduct()
+		product.code = row[0]
+		product.name = row[1]
+		product.brands = row[2]
+		product.serving, _ = strconv.ParseFloat(row[3], 64)
+		for i, column := range columns[required:] {
+			product.set(column, row[required+i])
+		}
+		return fn(product.food())
+	})
+}
+
+// sniffComma returns a tab when the header line of the file contains one and
+// a comma otherwise.
+func sniffComma(path string) (rune, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	header, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if strings.Contains(header, "\t") {
+		return '\t', nil
+	}
+	return ',', nil
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"assignment2/catalog"
+	"assignment2/store"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const offJSONL = `{"code": "4006381333931", "product_name": " Muesli ", "brands": "Acme, Acme Foods", "nutriments": {"energy-kcal_100g": 380, "proteins_100g": "9.5", "sodium_100g": 0.4, "salt_100g": 1}}
+{"code": "5901234123457", "product_name": "Granola bar", "serving_quantity": "25", "nutriments": {"proteins_serving": 2.5, "fat_100g": 20, "fat_serving": 99, "sugars_serving": -1}}
+{"code": "5901234123458", "product_name": "Wrong check digit", "nutriments": {"proteins_100g": 1}}
+{"code": "96385074", "product_name": "Per serving without a serving size", "nutriments": {"proteins_serving": 3}}
+`
+
+// readAll returns the foods read from a dump with the given contents.
+func readAll(t *testing.T, name string, contents string) []Food {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var foods []Food
+	if err := readOFF(path, func(food Food) error {
+		foods = append(foods, food)
+		return nil
+	}); err != nil {
+		t.Fatalf("readOFF: %v", err)
+	}
+	return foods
+}
+
+func TestReadOFFJSONL(t *testing.T) {
+	want := []Food{
+		{
+			Name:    "Muesli",
+			Barcode: "04006381333931",
+			Brand:   "Acme",
+			Nutrients: []store.NutrientValue{
+				{Name: "Energy", AmountPer100g: 380},
+				{Name: "Protein", AmountPer100g: 9.5},
+				// grams converted to the catalog unit
+				{Name: "Sodium", AmountPer100g: 400},
+			},
+		},
+		{
+			Name:    "Granola bar",
+			Barcode: "05901234123457",
+			Nutrients: []store.NutrientValue{
+				// per 100 g wins over per serving
+				{Name: "Fat", AmountPer100g: 20},
+				// 2.5 g in a 25 g serving
+				{Name: "Protein", AmountPer100g: 10},
+			},
+		},
+		// products without a valid barcode come back without a name
+		{},
+		{Name: "Per serving without a serving size", Barcode: "00000096385074"},
+	}
+	if got := readAll(t, "products.jsonl", offJSONL); !reflect.DeepEqual(got, want) {
+		t.Errorf("readOFF =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReadOFFCSV(t *testing.T) {
+	// the Open Food Facts CSV export is tab separated and unquoted
+	rows := [][]string{
+		{"code", "product_name", "brands", "serving_quantity", "proteins_100g", "vitamin-c_serving", "unrelated"},
+		{"4006381333931", `Muesli "classic"`, "Acme", "50", "9.5", "0.01", "x"},
+		{"036000291452", "Crackers", "", "", "", "0.01", ""},
+	}
+	var lines []string
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, "\t"))
+	}
+
+	want := []Food{
+		{
+			Name:    `Muesli "classic"`,
+			Barcode: "04006381333931",
+			Brand:   "Acme",
+			Nutrients: []store.NutrientValue{
+				{Name: "Protein", AmountPer100g: 9.5},
+				// 0.01 g in a 50 g serving is 20 mg per 100 g
+				{Name: "Vitamin C", AmountPer100g: 20},
+			},
+		},
+		{Name: "Crackers", Barcode: "00036000291452"},
+	}
+	if got := readAll(t, "products.csv", strings.Join(lines, "\n")+"\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("readOFF =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReadOFFRejectsUnknownExtension(t *testing.T) {
+	if err := readOFF("products.xml", func(Food) error { return nil }); err == nil {
+		t.Error("readOFF of an .xml file succeeded")
+	}
+}
+
+func TestImportOFF(t *testing.T) {
+	ctx := context.Background()
+	ingredients := store.NewMemory()
+	if err := ingredients.SyncNutrientCatalog(ctx, catalog.Nutrients); err != nil {
+		t.Fatalf("SyncNutrientCatalog: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "products.jsonl")
+	if err := os.WriteFile(path, []byte(offJSONL), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := Import(ctx, ingredients, SourceOFF, path, 1)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	// the invalid barcode and the product without nutrients are skipped
+	if want := (Summary{Read: 4, Created: 2, Skipped: 2}); *summary != want {
+		t.Errorf("first Import = %+v, want %+v", *summary, want)
+	}
+
+	summary, err = Import(ctx, ingredients, SourceOFF, path, 0)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if want := (Summary{Read: 4, Unchanged: 2, Skipped: 2}); *summary != want {
+		t.Errorf("second Import = %+v, want %+v", *summary, want)
+	}
+
+	muesli, err := ingredients.GetIngredientByBarcode(ctx, "04006381333931")
+	if err != nil {
+		t.Fatalf("GetIngredientByBarcode: %v", err)
+	}
+	if muesli.Name != "Muesli" || muesli.Brand != "Acme" || len(muesli.Nutrients) != 3 {
+		t.Errorf("imported product = %+v", muesli)
+	}
+
+	if _, err := Import(ctx, ingredients, "usda", path, 0); err == nil {
+		t.Error("Import from an unknown source succeeded")
+	}
+}
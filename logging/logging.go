@@ -0,0 +1,57 @@
+// Package logging sets up structured JSON logging with log/slog and the HTTP
+// middleware that tags every log record of a request with its request ID and
+// user, and writes one access log entry per request.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a JSON logger writing records at level and above to w. Records
+// logged with a request context carry the request's attributes, see
+// Middleware.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// ParseLevel parses debug, info, warn or error, in any case. An empty string
+// means info.
+func ParseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("log level must be one of debug, info, warn or error, got %q", s)
+	}
+	return level, nil
+}
+
+// contextHandler adds the attributes of the request and the trace span in the
+// context, if any, to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info := requestFromContext(ctx); info != nil {
+		record.AddAttrs(info.attrs()...)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
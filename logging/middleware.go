@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RequestIDHeader carries the request ID in both directions. An ID sent by
+// the client or a proxy in front of the server is kept, otherwise one is
+// generated.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the IDs accepted from clients.
+const maxRequestIDLength = 128
+
+type contextKey struct{}
+
+// requestInfo is shared between the middleware and the handlers further down
+// the chain, which add to it through SetUserID and Route.
+type requestInfo struct {
+	id string
+
+	mu     sync.Mutex
+	route  string
+	userID int64
+}
+
+func (info *requestInfo) attrs() []slog.Attr {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	attrs := []slog.Attr{slog.String("request_id", info.id)}
+	if info.userID != 0 {
+		attrs = append(attrs, slog.Int64("user_id", info.userID))
+	}
+	return attrs
+}
+
+func requestFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(contextKey{}).(*requestInfo)
+	return info
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+	if info := requestFromContext(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the request ctx belongs to.
+func SetUserID(ctx context.Context, userID int64) {
+	if info := requestFromContext(ctx); info != nil {
+		info.mu.Lock()
+		info.userID = userID
+		info.mu.Unlock()
+	}
+}
+
+// Middleware assigns each request an ID, echoes it in the X-Request-ID
+// response header and logs the request once it has been served. It wraps the
+// whole router so that requests matching no route are logged too.
+func Middleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{id: requestID(r)}
+		w.Header().Set(RequestIDHeader, info.id)
+		ctx := context.WithValue(r.Context(), contextKey{}, info)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		info.mu.Lock()
+		route := info.route
+		info.mu.Unlock()
+		level := slog.LevelInfo
+		if recorder.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(ctx, level, "Request served",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.statusCode()),
+			slog.Int64("bytes", recorder.bytes),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+// Route is a mux middleware recording the path template of the matched
+// route, such as /api/meals/{id}, for the access log. The template keeps the
+// number of distinct routes small, unlike the path.
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := requestFromContext(r.Context()); info != nil {
+			if route := mux.CurrentRoute(r); route != nil {
+				template, _ := route.GetPathTemplate()
+				info.mu.Lock()
+				info.route = template
+				info.mu.Unlock()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); id != "" && len(id) <= maxRequestIDLength && printable(id) {
+		return id
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// printable keeps IDs that would garble the log or the header out.
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder remembers the status and size of the response. Unwrap lets
+// http.ResponseController reach the connection, as the export handler does to
+// extend its write deadline.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// statusCode is the status sent, 200 when the handler wrote nothing at all.
+func (rec *statusRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
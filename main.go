@@ -3,61 +3,183 @@
 package main
 
 import (
+	"assignment2/auth"
+	"assignment2/catalog"
+	"assignment2/config"
 	"assignment2/handlers"
+	"assignment2/health"
+	"assignment2/logging"
+	"assignment2/metrics"
+	"assignment2/openapi"
+	"assignment2/store"
+	"assignment2/tracing"
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
-
 func main() {
-	// Initialize database
-	db := initDB()
-	defer db.Close()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImport(os.Args[2:])
+		return
+	}
 
-	port := os.Getenv("APP_PORT")
-	if port == "" {
-		port = "8080" // Default port if not specified
+	cfg, printConfig, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if printConfig {
+		for _, attr := range cfg.Redacted() {
+			fmt.Println(attr)
+		}
+		return
 	}
 
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logger := logging.New(os.Stderr, level)
+	// also routes the log package, still used for startup messages
+	slog.SetDefault(logger)
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "Effective configuration", cfg.Redacted()...)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize storage, Postgres unless STORAGE=memory
+	var st store.Store
+	var db *sql.DB
+	switch cfg.Storage {
+	case "postgres":
+		db = initDB(cfg.Database)
+		defer db.Close()
+		metrics.RegisterDB(db, cfg.Database.Name)
+		st = store.NewPostgres(db)
+	case "memory":
+		log.Println("Using in-memory storage, data will be lost on shutdown")
+		st = store.NewMemory()
+	}
+
+	if err := st.SyncNutrientCatalog(context.Background(), catalog.Nutrients); err != nil {
+		log.Fatalf("Syncing the nutrient catalog: %v", err)
+	}
+
+	checker, err := health.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authSecret := []byte(cfg.Auth.Secret)
+	if len(authSecret) == 0 {
+		log.Println("AUTH_SECRET is not set, using a random secret; tokens will not survive a restart")
+		authSecret = make([]byte, 32)
+		if _, err := rand.Read(authSecret); err != nil {
+			log.Fatal(err)
+		}
+	}
+	tokens := auth.NewTokens(authSecret, cfg.Auth.TokenTTL)
+
 	r := mux.NewRouter()
-	r.HandleFunc("/health", healthHandler).Methods("GET")
-
-	mealHandler := handlers.NewMealHandler(db)
-	r.HandleFunc("/api/meals", mealHandler.CreateMealHandle).Methods("POST")
-	r.HandleFunc("/api/meals/{id}", mealHandler.GetMealHandle).Methods("GET")
-	r.HandleFunc("/api/meals/{id}/ingredients", mealHandler.AddIngredientToMealHandle).Methods("PUT")
-	r.HandleFunc("/api/meals/{id}/ingredients/{ingredient_id}", mealHandler.RemoveIngredientFromMealHandle).Methods("DELETE")
-	r.HandleFunc("/api/meals/{id}/ingredients", mealHandler.UpdateIngredientInMealHandle).Methods("PUT")
-	r.HandleFunc("/api/meals/{id}", mealHandler.DeleteMealHandle).Methods("DELETE")
-
-	ingredientHandler := handlers.NewIngredientHandler(db)
-	r.HandleFunc("/api/ingredients", ingredientHandler.CreateIngredientHandle).Methods("POST")
-	r.HandleFunc("/api/ingredients/{id}", ingredientHandler.GetIngredientHandle).Methods("GET")
-	r.HandleFunc("/api/ingredients/{id}", ingredientHandler.UpdateIngredientHandle).Methods("PUT")
-	r.HandleFunc("/api/ingredients/{id}", ingredientHandler.DeleteIngredientHandle).Methods("DELETE")
+	r.Use(logging.Route, metrics.Middleware, tracing.Route)
+	r.HandleFunc("/health", checker.HealthHandle).Methods("GET")
+	r.HandleFunc("/ready", checker.ReadyHandle).Methods("GET")
+	r.HandleFunc("/openapi.json", openapi.Handler).Methods("GET")
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	authHandler := handlers.NewAuthHandler(st, tokens)
+	r.HandleFunc("/api/auth/register", authHandler.RegisterHandle).Methods("POST")
+	r.HandleFunc("/api/auth/login", authHandler.LoginHandle).Methods("POST")
+
+	// every other /api route requires a bearer token
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(auth.Middleware(tokens))
+	api.HandleFunc("/auth/me", authHandler.MeHandle).Methods("GET")
+
+	mealHandler := handlers.NewMealHandler(st)
+	api.HandleFunc("/meals", mealHandler.ListMealsHandle).Methods("GET")
+	api.HandleFunc("/meals", mealHandler.CreateMealHandle).Methods("POST")
+	api.HandleFunc("/meals/{id}", mealHandler.GetMealHandle).Methods("GET")
+	api.HandleFunc("/meals/{id}/nutrition", mealHandler.GetMealNutritionHandle).Methods("GET")
+	api.HandleFunc("/meals/{id}/ingredients", mealHandler.AddIngredientToMealHandle).Methods("POST")
+	api.HandleFunc("/meals/{id}/ingredients/{ingredient_id}", mealHandler.UpdateIngredientInMealHandle).Methods("PUT")
+	api.HandleFunc("/meals/{id}/ingredients/{ingredient_id}", mealHandler.RemoveIngredientFromMealHandle).Methods("DELETE")
+	api.HandleFunc("/meals/{id}", mealHandler.UpdateMealHandle).Methods("PATCH")
+	api.HandleFunc("/meals/{id}", mealHandler.DeleteMealHandle).Methods("DELETE")
+
+	ingredientHandler := handlers.NewIngredientHandler(st)
+	api.HandleFunc("/ingredients", ingredientHandler.ListIngredientsHandle).Methods("GET")
+	api.HandleFunc("/ingredients", ingredientHandler.CreateIngredientHandle).Methods("POST")
+	api.HandleFunc("/ingredients/by-barcode/{code}", ingredientHandler.GetIngredientByBarcodeHandle).Methods("GET")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.GetIngredientHandle).Methods("GET")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.UpdateIngredientHandle).Methods("PUT")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.PatchIngredientHandle).Methods("PATCH")
+	api.HandleFunc("/ingredients/{id}", ingredientHandler.DeleteIngredientHandle).Methods("DELETE")
+
+	recipeHandler := handlers.NewRecipeHandler(st)
+	api.HandleFunc("/recipes", recipeHandler.CreateRecipeHandle).Methods("POST")
+	api.HandleFunc("/recipes/{id}", recipeHandler.GetRecipeHandle).Methods("GET")
+	api.HandleFunc("/recipes/{id}", recipeHandler.UpdateRecipeHandle).Methods("PUT")
+	api.HandleFunc("/recipes/{id}", recipeHandler.DeleteRecipeHandle).Methods("DELETE")
+
+	importHandler := handlers.NewImportHandler(st, cfg.ImportDir)
+	api.HandleFunc("/imports", importHandler.CreateImportHandle).Methods("POST")
+
+	nutrientHandler := handlers.NewNutrientHandler(st)
+	api.HandleFunc("/nutrients", nutrientHandler.ListNutrientsHandle).Methods("GET")
+
+	goalHandler := handlers.NewGoalHandler(st, st)
+	api.HandleFunc("/goals", goalHandler.ListGoalsHandle).Methods("GET")
+	api.HandleFunc("/goals/progress", goalHandler.GoalProgressHandle).Methods("GET")
+	api.HandleFunc("/goals/{nutrient}", goalHandler.SetGoalHandle).Methods("PUT")
+	api.HandleFunc("/goals/{nutrient}", goalHandler.DeleteGoalHandle).Methods("DELETE")
+
+	exportHandler := handlers.NewExportHandler(st)
+	api.HandleFunc("/export", exportHandler.ExportHandle).Methods("GET")
+
+	reportHandler := handlers.NewReportHandler(st)
+	api.HandleFunc("/reports/daily", reportHandler.DailyReportHandle).Methods("GET")
+	api.HandleFunc("/reports/weekly", reportHandler.WeeklyReportHandle).Methods("GET")
 
 	srv := &http.Server{
-		Handler:      r,
-		Addr:         ":" + port,
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+		Handler:           tracing.Handler(logging.Middleware(logger, r)),
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
 
 	go func() {
-		log.Println("Starting the HTTP server on port 8080")
-		if err := srv.ListenAndServe(); err != nil {
-			log.Println(err)
+		var err error
+		if cfg.TLS.Enabled() {
+			slog.Info("Starting the HTTPS server", "port", cfg.Server.Port)
+			err = srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			slog.Info("Starting the HTTP server", "port", cfg.Server.Port)
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
 		}
 	}()
 
@@ -65,9 +187,18 @@ func main() {
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	<-c
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	checker.Drain()
+	if cfg.Server.DrainDelay > 0 {
+		log.Printf("Draining for %s before shutting down\n", cfg.Server.DrainDelay)
+		time.Sleep(cfg.Server.DrainDelay)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
-	srv.Shutdown(ctx)
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Println(err)
+	}
 	log.Println("shutting down")
-	os.Exit(0)
 }
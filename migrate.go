@@ -0,0 +1,80 @@
+package main
+
+import (
+	"assignment2/config"
+	"assignment2/migrations"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = "usage: myhttpserver migrate [-config FILE] [setting flags] up|down|status|goto VERSION"
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	loadConfig := config.AddFlags(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db := openDB(cfg.Database)
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "goto":
+		if len(args) != 2 {
+			log.Fatal(migrateUsage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Fatalf("Invalid migration version %q", args[1])
+		}
+		err = migrator.Goto(ctx, version)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	default:
+		log.Fatal(migrateUsage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,323 @@
+// Package migrations applies the versioned schema migrations embedded from
+// the sql directory. Each migration is a pair of files named
+// NNNN_description.up.sql and NNNN_description.down.sql, and applied versions
+// are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey identifies the Postgres advisory lock held while migrating so that
+// several instances starting at once do not race each other.
+const lockKey = 7_402_133_155
+
+const schemaMigrationsTableCreateSQL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    Version BIGINT PRIMARY KEY,
+    Name VARCHAR(255) NOT NULL,
+    AppliedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a known migration and whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load reads the embedded migrations ordered by version.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		base := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", base)
+		}
+
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_description", base)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", base, prefix)
+		}
+
+		contents, err := files.ReadFile(path.Join("sql", base))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s is missing its up or down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest returns the highest known migration version.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		current := currentVersion(applied)
+		if current == 0 {
+			log.Println("No migrations to roll back")
+			return nil
+		}
+		previous := 0
+		for version := range applied {
+			if version < current && version > previous {
+				previous = version
+			}
+		}
+		return m.migrate(ctx, conn, applied, previous)
+	})
+}
+
+// Goto migrates up or down until version is the latest applied migration.
+// Version 0 rolls back every migration.
+func (m *Migrator) Goto(ctx context.Context, version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, applied, version)
+	})
+}
+
+// Version returns the latest applied migration version, or 0 when none is.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	applied, err := appliedVersions(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+	return currentVersion(applied), nil
+}
+
+// Status reports every known migration along with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := appliedVersions(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// migrate applies pending migrations up to target and rolls back applied
+// migrations above it, newest first.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, applied map[int]time.Time, target int) error {
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		log.Printf("Rolling back migration %04d_%s\n", migration.Version, migration.Name)
+		err := runInTx(ctx, conn, migration.Down, "DELETE FROM schema_migrations WHERE Version = $1", migration.Version)
+		if err != nil {
+			return fmt.Errorf("rolling back migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		log.Printf("Applying migration %04d_%s\n", migration.Version, migration.Name)
+		err := runInTx(ctx, conn, migration.Up, "INSERT INTO schema_migrations (Version, Name) VALUES ($1, $2)", migration.Version, migration.Name)
+		if err != nil {
+			return fmt.Errorf("applying migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// withLock runs fn on a dedicated connection while holding the migration
+// advisory lock. The schema_migrations table is created first if needed.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	if _, err := conn.ExecContext(ctx, schemaMigrationsTableCreateSQL); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+	return fn(conn)
+}
+
+// runInTx executes a migration script and its schema_migrations bookkeeping
+// statement in a single transaction.
+func runInTx(ctx context.Context, conn *sql.Conn, script string, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// querier is satisfied by *sql.DB and *sql.Conn.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// appliedVersions returns the applied migration versions and when they were
+// applied. A missing schema_migrations table means nothing was applied yet.
+func appliedVersions(ctx context.Context, q querier) (map[int]time.Time, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("checking for schema_migrations table: %w", err)
+	}
+	applied := make(map[int]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT Version, AppliedAt FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("querying schema_migrations table: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scanning schema migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func currentVersion(applied map[int]time.Time) int {
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current
+}
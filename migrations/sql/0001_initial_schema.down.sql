@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS Meal_Ingredients;
+DROP TABLE IF EXISTS Meals;
+DROP TABLE IF EXISTS Nutrient_Values;
+DROP TABLE IF EXISTS Nutrients;
+DROP TABLE IF EXISTS Ingredients;
+DROP TABLE IF EXISTS Users;
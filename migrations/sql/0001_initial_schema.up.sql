@@ -0,0 +1,52 @@
+-- Tables previously created by initDB. IF NOT EXISTS keeps this migration
+-- safe to apply on databases that were initialised before migrations existed.
+
+CREATE TABLE IF NOT EXISTS Users (
+    UserID SERIAL PRIMARY KEY,
+    Username VARCHAR(255) NOT NULL UNIQUE,
+    Email VARCHAR(255) NOT NULL UNIQUE,
+    PasswordHash VARCHAR(255) NOT NULL,
+    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS Ingredients (
+    IngredientID SERIAL PRIMARY KEY,
+    Name VARCHAR(255) NOT NULL,
+    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS Nutrients (
+    NutrientID SERIAL PRIMARY KEY,
+    Name VARCHAR(255) NOT NULL,
+    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS Nutrient_Values (
+    IngredientID INT NOT NULL,
+    NutrientID INT NOT NULL,
+    AmountPer100g NUMERIC(10,2) NOT NULL,
+    PRIMARY KEY (IngredientID, NutrientID),
+    FOREIGN KEY (IngredientID) REFERENCES Ingredients(IngredientID) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (NutrientID) REFERENCES Nutrients(NutrientID) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Meals (
+    MealID SERIAL PRIMARY KEY,
+    Name VARCHAR(255) NOT NULL,
+    Date DATE NOT NULL,
+    Time TIME NOT NULL,
+    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS Meal_Ingredients (
+    MealID INT NOT NULL,
+    IngredientID INT NOT NULL,
+    QuantityInGrams NUMERIC(10,2) NOT NULL,
+    PRIMARY KEY (MealID, IngredientID),
+    FOREIGN KEY (MealID) REFERENCES Meals(MealID) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (IngredientID) REFERENCES Ingredients(IngredientID) ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS meals_userid_date_idx;
+ALTER TABLE Meals DROP CONSTRAINT IF EXISTS meals_userid_fkey;
+ALTER TABLE Meals DROP COLUMN IF EXISTS UserID;
+
+DELETE FROM Users WHERE Username = 'legacy' AND PasswordHash = '!';
@@ -0,0 +1,27 @@
+-- Meals existing before ownership was introduced are assigned to a "legacy"
+-- user. Its password hash is not a valid bcrypt hash so nobody can log in as it,
+-- which also tells it apart from a real user who registered as "legacy".
+INSERT INTO Users (Username, Email, PasswordHash)
+SELECT 'legacy', 'legacy@localhost', '!'
+WHERE EXISTS (SELECT 1 FROM Meals)
+ON CONFLICT DO NOTHING;
+
+-- A real user holding the name or the email must not be given the meals of
+-- everyone else, so the migration stops instead.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM Meals)
+        AND NOT EXISTS (SELECT 1 FROM Users WHERE Username = 'legacy' AND PasswordHash = '!') THEN
+        RAISE EXCEPTION 'cannot create the legacy owner of existing meals: the username "legacy" or the email "legacy@localhost" is taken by a real user';
+    END IF;
+END
+$$;
+
+ALTER TABLE Meals ADD COLUMN UserID INT;
+
+UPDATE Meals SET UserID = (SELECT UserID FROM Users WHERE Username = 'legacy' AND PasswordHash = '!') WHERE UserID IS NULL;
+
+ALTER TABLE Meals ALTER COLUMN UserID SET NOT NULL;
+ALTER TABLE Meals ADD CONSTRAINT meals_userid_fkey FOREIGN KEY (UserID) REFERENCES Users(UserID) ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE INDEX meals_userid_date_idx ON Meals (UserID, Date);
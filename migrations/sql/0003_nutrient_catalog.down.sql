@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS nutrients_catalog_name_idx;
+
+ALTER TABLE Nutrients
+    DROP COLUMN Category,
+    DROP COLUMN Unit;
@@ -0,0 +1,9 @@
+-- Units and categories of the nutrient catalog. The catalog rows themselves
+-- are written by the application on startup; rows that cannot be matched to
+-- the catalog keep a NULL Unit.
+
+ALTER TABLE Nutrients
+    ADD COLUMN Unit VARCHAR(16),
+    ADD COLUMN Category VARCHAR(16);
+
+CREATE UNIQUE INDEX nutrients_catalog_name_idx ON Nutrients (Name) WHERE Unit IS NOT NULL;
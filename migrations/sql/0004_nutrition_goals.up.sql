@@ -0,0 +1,14 @@
+-- Daily nutrient targets per user. A goal has a minimum, a maximum or both.
+
+CREATE TABLE Nutrition_Goals (
+    UserID INT NOT NULL,
+    NutrientID INT NOT NULL,
+    MinAmount NUMERIC(10,2),
+    MaxAmount NUMERIC(10,2),
+    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (UserID, NutrientID),
+    FOREIGN KEY (UserID) REFERENCES Users(UserID) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (NutrientID) REFERENCES Nutrients(NutrientID) ON UPDATE CASCADE ON DELETE CASCADE,
+    CHECK (MinAmount IS NOT NULL OR MaxAmount IS NOT NULL),
+    CHECK (MinAmount <= MaxAmount)
+);
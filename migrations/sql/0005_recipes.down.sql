@@ -0,0 +1,4 @@
+-- Recipe rows stay behind as plain ingredients with their last derived
+-- nutrient values.
+DROP TABLE IF EXISTS Recipe_Ingredients;
+DROP TABLE IF EXISTS Recipes;
@@ -0,0 +1,22 @@
+-- A recipe is an Ingredients row whose Nutrient_Values are derived from the
+-- ingredients it is made of and its cooked yield. Components must be plain
+-- ingredients and cannot be deleted while a recipe uses them.
+
+CREATE TABLE Recipes (
+    IngredientID INT PRIMARY KEY,
+    YieldInGrams NUMERIC(10,2) NOT NULL CHECK (YieldInGrams > 0),
+    CreatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UpdatedAt TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (IngredientID) REFERENCES Ingredients(IngredientID) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE Recipe_Ingredients (
+    RecipeID INT NOT NULL,
+    IngredientID INT NOT NULL,
+    QuantityInGrams NUMERIC(10,2) NOT NULL,
+    PRIMARY KEY (RecipeID, IngredientID),
+    FOREIGN KEY (RecipeID) REFERENCES Recipes(IngredientID) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (IngredientID) REFERENCES Ingredients(IngredientID) ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX recipe_ingredients_ingredientid_idx ON Recipe_Ingredients (IngredientID);
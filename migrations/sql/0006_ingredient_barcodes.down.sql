@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS ingredients_barcode_idx;
+
+ALTER TABLE Ingredients
+    DROP COLUMN Brand,
+    DROP COLUMN Barcode;
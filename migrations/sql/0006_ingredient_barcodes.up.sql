@@ -0,0 +1,8 @@
+-- Packaged products carry their barcode, normalised to a 14 digit GTIN, and
+-- brand. Both stay NULL for generic ingredients.
+
+ALTER TABLE Ingredients
+    ADD COLUMN Barcode VARCHAR(14),
+    ADD COLUMN Brand VARCHAR(255);
+
+CREATE UNIQUE INDEX ingredients_barcode_idx ON Ingredients (Barcode) WHERE Barcode IS NOT NULL;
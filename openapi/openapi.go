@@ -0,0 +1,19 @@
+// Package openapi embeds the OpenAPI 3 document describing the HTTP API. The
+// request schemas mirror the validate tags of the handlers package; keep the
+// two in step when changing either.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+// Handler serves the document, GET /openapi.json.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(Spec)
+}
@@ -0,0 +1,63 @@
+// Package problem writes RFC 7807 problem details, the error body of every
+// API response. Besides the standard members each problem carries a stable,
+// machine readable code such as "meal_not_found" that clients can switch on
+// instead of parsing the detail text.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// Problem is one error response. Problems declared as package variables are
+// shared between requests and must not be modified; use With to vary them.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+	// Errors lists the invalid fields of a validation_failed problem.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid request field. Field is the JSON path of
+// the field, such as "ingredients[1].amount_in_grams".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New returns a problem whose type is derived from code and whose title is the
+// standard text of status.
+func New(status int, code string, detail string) *Problem {
+	return &Problem{
+		Type:   "/problems/" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// With returns a copy of p with another detail.
+func (p *Problem) With(detail string) *Problem {
+	copied := *p
+	copied.Detail = detail
+	return &copied
+}
+
+// Write sends p as the response.
+func Write(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,74 @@
+package reports
+
+import (
+	"assignment2/store"
+	"context"
+	"time"
+)
+
+// Goal statuses.
+const (
+	StatusUnder  = "under"
+	StatusWithin = "within"
+	StatusOver   = "over"
+)
+
+// GoalProgress compares a day's intake of one nutrient with the user's goal.
+type GoalProgress struct {
+	Goal   store.Goal
+	Intake float64
+	// Percent is the intake relative to the goal's minimum, or to its maximum
+	// when it has no minimum.
+	Percent float64
+	Status  string
+}
+
+// Progress is the progress of every goal of a user on one day.
+type Progress struct {
+	Date  time.Time
+	Goals []GoalProgress
+}
+
+// DailyProgress computes the user's intake on date and compares it with each
+// of their goals. Nutrients that were not eaten count as zero intake.
+func DailyProgress(ctx context.Context, meals store.MealStore, goals store.GoalStore, userID int64, date time.Time) (*Progress, error) {
+	userGoals, err := goals.ListGoals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := Daily(ctx, meals, userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	intake := make(map[int64]float64)
+	for _, total := range report.Totals {
+		intake[total.NutrientID] = total.Amount
+	}
+
+	progress := &Progress{Date: report.From}
+	for _, goal := range userGoals {
+		progress.Goals = append(progress.Goals, compare(goal, intake[goal.NutrientID]))
+	}
+	return progress, nil
+}
+
+func compare(goal store.Goal, intake float64) GoalProgress {
+	progress := GoalProgress{Goal: goal, Intake: intake, Status: StatusWithin}
+
+	reference := goal.Max
+	if goal.Min != nil && *goal.Min > 0 {
+		reference = goal.Min
+	}
+	if reference != nil && *reference > 0 {
+		progress.Percent = intake / *reference * 100
+	}
+
+	switch {
+	case goal.Min != nil && intake < *goal.Min:
+		progress.Status = StatusUnder
+	case goal.Max != nil && intake > *goal.Max:
+		progress.Status = StatusOver
+	}
+	return progress
+}
@@ -0,0 +1,143 @@
+// Package reports aggregates per-meal nutrient totals into daily and weekly
+// nutrition summaries.
+package reports
+
+import (
+	"assignment2/store"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report summarises every meal of a user between From and To inclusive.
+type Report struct {
+	From time.Time
+	To   time.Time
+	// Days is the number of calendar days in the period and DaysWithMeals
+	// how many of them have at least one meal.
+	Days          int
+	DaysWithMeals int
+	Totals        []store.NutrientTotal
+	// AveragePerDay divides Totals by Days.
+	AveragePerDay []store.NutrientTotal
+	PerDay        []Day
+	Meals         []store.MealNutritionSummary
+}
+
+// Day holds the totals of a single calendar day.
+type Day struct {
+	Date   time.Time
+	Totals []store.NutrientTotal
+}
+
+// Build loads the meals of the period from meals and aggregates them.
+func Build(ctx context.Context, meals store.MealStore, userID int64, from time.Time, to time.Time) (*Report, error) {
+	summaries, err := meals.ListMealNutrition(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{From: from, To: to, Meals: summaries}
+	totals := make(map[int64]*store.NutrientTotal)
+	perDay := make(map[string]map[int64]*store.NutrientTotal)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		report.Days++
+		perDay[day.Format(time.DateOnly)] = make(map[int64]*store.NutrientTotal)
+	}
+
+	logged := make(map[string]bool)
+	for _, meal := range summaries {
+		day := meal.Date.Format(time.DateOnly)
+		logged[day] = true
+		dayTotals := perDay[day]
+		for _, total := range meal.Totals {
+			add(totals, total, total.Amount)
+			add(dayTotals, total, total.Amount)
+		}
+	}
+
+	report.Totals = sorted(totals)
+	for _, total := range report.Totals {
+		report.AveragePerDay = append(report.AveragePerDay, store.NutrientTotal{
+			NutrientID: total.NutrientID,
+			Name:       total.Name,
+			Unit:       total.Unit,
+			Amount:     total.Amount / float64(report.Days),
+		})
+	}
+
+	report.DaysWithMeals = len(logged)
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		report.PerDay = append(report.PerDay, Day{Date: day, Totals: sorted(perDay[day.Format(time.DateOnly)])})
+	}
+	return report, nil
+}
+
+// Daily builds the report of a single day.
+func Daily(ctx context.Context, meals store.MealStore, userID int64, date time.Time) (*Report, error) {
+	day := truncateToDay(date)
+	return Build(ctx, meals, userID, day, day)
+}
+
+// Weekly builds the report of the ISO week starting on monday.
+func Weekly(ctx context.Context, meals store.MealStore, userID int64, monday time.Time) (*Report, error) {
+	start := truncateToDay(monday)
+	return Build(ctx, meals, userID, start, start.AddDate(0, 0, 6))
+}
+
+// ParseISOWeek parses a week such as "2023-W47" and returns its monday.
+func ParseISOWeek(week string) (time.Time, error) {
+	yearPart, weekPart, ok := strings.Cut(week, "-W")
+	if !ok {
+		return time.Time{}, fmt.Errorf("week must be formatted as YYYY-Www")
+	}
+	year, err := strconv.Atoi(yearPart)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("week must be formatted as YYYY-Www")
+	}
+	number, err := strconv.Atoi(weekPart)
+	if err != nil || number < 1 || number > 53 {
+		return time.Time{}, fmt.Errorf("week must be formatted as YYYY-Www")
+	}
+
+	// January 4th is always in week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(number-1)*7)
+	if y, w := monday.ISOWeek(); y != year || w != number {
+		return time.Time{}, fmt.Errorf("%d has no week %d", year, number)
+	}
+	return monday, nil
+}
+
+// StartOfISOWeek returns the monday of the ISO week containing t.
+func StartOfISOWeek(t time.Time) time.Time {
+	day := truncateToDay(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func add(totals map[int64]*store.NutrientTotal, nutrient store.NutrientTotal, amount float64) {
+	if total, ok := totals[nutrient.NutrientID]; ok {
+		total.Amount += amount
+		return
+	}
+	totals[nutrient.NutrientID] = &store.NutrientTotal{NutrientID: nutrient.NutrientID, Name: nutrient.Name, Unit: nutrient.Unit, Amount: amount}
+}
+
+func sorted(totals map[int64]*store.NutrientTotal) []store.NutrientTotal {
+	var result []store.NutrientTotal
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
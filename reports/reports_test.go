@@ -0,0 +1,140 @@
+package reports
+
+import (
+	"assignment2/catalog"
+	"assignment2/store"
+	"context"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseISOWeek(t *testing.T) {
+	tests := []struct {
+		week string
+		want time.Time
+	}{
+		{"2023-W47", date(2023, time.November, 20)},
+		// week 1 contains January 4th, so it can start in the previous year
+		{"2019-W01", date(2018, time.December, 31)},
+		{"2026-W01", date(2025, time.December, 29)},
+		// and a year's first days can belong to the last week of the previous one
+		{"2020-W53", date(2020, time.December, 28)},
+		{"2021-W01", date(2021, time.January, 4)},
+	}
+	for _, test := range tests {
+		got, err := ParseISOWeek(test.week)
+		if err != nil {
+			t.Errorf("ParseISOWeek(%q): %v", test.week, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("ParseISOWeek(%q) = %s, want %s", test.week, got.Format(time.DateOnly), test.want.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestParseISOWeekRejects(t *testing.T) {
+	for _, week := range []string{"", "2023", "2023-47", "2023-W", "2023-W00", "2023-W54", "abcd-W01", "2021-W53"} {
+		if _, err := ParseISOWeek(week); err == nil {
+			t.Errorf("ParseISOWeek(%q) succeeded, want an error", week)
+		}
+	}
+}
+
+func TestStartOfISOWeek(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want time.Time
+	}{
+		{time.Date(2023, time.November, 20, 0, 0, 0, 0, time.UTC), date(2023, time.November, 20)},
+		{time.Date(2023, time.November, 22, 13, 45, 0, 0, time.UTC), date(2023, time.November, 20)},
+		// sunday ends the ISO week
+		{time.Date(2023, time.November, 26, 23, 59, 59, 0, time.UTC), date(2023, time.November, 20)},
+		{time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC), date(2020, time.December, 28)},
+	}
+	for _, test := range tests {
+		if got := StartOfISOWeek(test.t); !got.Equal(test.want) {
+			t.Errorf("StartOfISOWeek(%s) = %s, want %s", test.t, got.Format(time.DateOnly), test.want.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestWeeklyBucketsMealsByDay(t *testing.T) {
+	ctx := context.Background()
+	meals := store.NewMemory()
+	if err := meals.SyncNutrientCatalog(ctx, catalog.Nutrients); err != nil {
+		t.Fatalf("SyncNutrientCatalog: %v", err)
+	}
+	userID, err := meals.CreateUser(ctx, &store.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	ingredientID, err := meals.CreateIngredient(ctx, &store.Ingredient{Name: "Oats", Nutrients: []store.NutrientValue{{Name: "Energy", AmountPer100g: 400}}})
+	if err != nil {
+		t.Fatalf("CreateIngredient: %v", err)
+	}
+
+	// 100 g of oats is 400 kcal
+	for _, at := range []time.Time{
+		time.Date(2023, time.November, 19, 23, 0, 0, 0, time.UTC), // sunday of the previous week
+		time.Date(2023, time.November, 20, 8, 0, 0, 0, time.UTC),
+		time.Date(2023, time.November, 20, 19, 0, 0, 0, time.UTC),
+		time.Date(2023, time.November, 26, 21, 0, 0, 0, time.UTC),
+		time.Date(2023, time.November, 27, 0, 30, 0, 0, time.UTC), // monday of the next week
+	} {
+		_, err := meals.CreateMeal(ctx, &store.Meal{
+			UserID:      userID,
+			Name:        "Porridge",
+			Date:        at,
+			Time:        at,
+			Ingredients: []store.MealIngredient{{IngredientID: ingredientID, AmountInGrams: 100}},
+		})
+		if err != nil {
+			t.Fatalf("CreateMeal: %v", err)
+		}
+	}
+
+	monday, err := ParseISOWeek("2023-W47")
+	if err != nil {
+		t.Fatalf("ParseISOWeek: %v", err)
+	}
+	report, err := Weekly(ctx, meals, userID, monday)
+	if err != nil {
+		t.Fatalf("Weekly: %v", err)
+	}
+
+	if !report.From.Equal(date(2023, time.November, 20)) || !report.To.Equal(date(2023, time.November, 26)) {
+		t.Errorf("report period = %s to %s", report.From.Format(time.DateOnly), report.To.Format(time.DateOnly))
+	}
+	if report.Days != 7 || report.DaysWithMeals != 2 || len(report.Meals) != 3 {
+		t.Fatalf("Days = %d, DaysWithMeals = %d, %d meals; want 7, 2, 3", report.Days, report.DaysWithMeals, len(report.Meals))
+	}
+	if len(report.Totals) != 1 || report.Totals[0].Amount != 1200 {
+		t.Errorf("Totals = %+v, want 1200 kcal", report.Totals)
+	}
+	if len(report.AveragePerDay) != 1 || report.AveragePerDay[0].Amount != 1200.0/7 {
+		t.Errorf("AveragePerDay = %+v, want 1200/7 kcal", report.AveragePerDay)
+	}
+
+	wantPerDay := []float64{800, 0, 0, 0, 0, 0, 400}
+	if len(report.PerDay) != len(wantPerDay) {
+		t.Fatalf("PerDay has %d days, want %d", len(report.PerDay), len(wantPerDay))
+	}
+	for i, want := range wantPerDay {
+		day := report.PerDay[i]
+		if !day.Date.Equal(monday.AddDate(0, 0, i)) {
+			t.Errorf("PerDay[%d].Date = %s", i, day.Date.Format(time.DateOnly))
+		}
+		var got float64
+		for _, total := range day.Totals {
+			got += total.Amount
+		}
+		if got != want {
+			t.Errorf("PerDay[%d] (%s) = %v kcal, want %v", i, day.Date.Format(time.DateOnly), got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"assignment2/catalog"
+)
+
+// catalogSync describes how to bring the Nutrients rows in line with one
+// catalog nutrient: keep (0 when a row must be inserted) is updated to the
+// catalog's name, unit and category and the rows in merge are folded into it.
+type catalogSync struct {
+	nutrient catalog.Nutrient
+	keep     int64
+	merge    []int64
+}
+
+// planCatalogSync matches the existing nutrient rows, ordered by ID, against
+// the catalog. A row already named exactly like the catalog nutrient is
+// preferred, otherwise the oldest matching row is kept.
+func planCatalogSync(existing []Nutrient, nutrients []catalog.Nutrient) []catalogSync {
+	plans := make([]catalogSync, len(nutrients))
+	index := make(map[string]int, len(nutrients))
+	for i, nutrient := range nutrients {
+		plans[i].nutrient = nutrient
+		index[nutrient.Name] = i
+	}
+
+	for _, row := range existing {
+		match, ok := catalog.Lookup(row.Name)
+		if !ok {
+			continue
+		}
+		i, ok := index[match.Name]
+		if !ok {
+			continue
+		}
+		plan := &plans[i]
+		switch {
+		case plan.keep == 0:
+			plan.keep = row.NutrientID
+		case row.Name == match.Name && nameOf(existing, plan.keep) != match.Name:
+			plan.merge = append(plan.merge, plan.keep)
+			plan.keep = row.NutrientID
+		default:
+			plan.merge = append(plan.merge, row.NutrientID)
+		}
+	}
+	return plans
+}
+
+func nameOf(nutrients []Nutrient, nutrientID int64) string {
+	for _, nutrient := range nutrients {
+		if nutrient.NutrientID == nutrientID {
+			return nutrient.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"assignment2/catalog"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPlanCatalogSync(t *testing.T) {
+	nutrients := []catalog.Nutrient{
+		{Name: "Energy", Unit: catalog.UnitKcal},
+		{Name: "Protein", Unit: catalog.UnitGram},
+		{Name: "Sodium", Unit: catalog.UnitMilligram},
+	}
+	existing := []Nutrient{
+		{NutrientID: 1, Name: "calories"},
+		{NutrientID: 2, Name: "protein"},
+		{NutrientID: 3, Name: "Protein"},
+		{NutrientID: 4, Name: "Protein (g)"},
+		{NutrientID: 5, Name: "Kcal"},
+		{NutrientID: 6, Name: "Unobtainium"},
+		// a unit other than the catalog's does not match
+		{NutrientID: 7, Name: "Sodium (g)"},
+	}
+
+	want := []catalogSync{
+		// without an exact name the oldest row is kept
+		{nutrient: nutrients[0], keep: 1, merge: []int64{5}},
+		// the row named exactly like the catalog wins over older ones
+		{nutrient: nutrients[1], keep: 3, merge: []int64{2, 4}},
+		// nothing matches, so a row has to be inserted
+		{nutrient: nutrients[2]},
+	}
+	if got := planCatalogSync(existing, nutrients); !reflect.DeepEqual(got, want) {
+		t.Errorf("planCatalogSync =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestMemorySyncNutrientCatalogMergesAliases(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	// rows and values written before the catalog existed
+	m.nutrients[1] = &Nutrient{NutrientID: 1, Name: "protein"}
+	m.nutrients[2] = &Nutrient{NutrientID: 2, Name: "Proteins"}
+	m.nutrients[3] = &Nutrient{NutrientID: 3, Name: "Unobtainium"}
+	m.nextNutrientID = 3
+	m.ingredients[1] = "Oats"
+	m.ingredients[2] = "Lentils"
+	m.nextIngredientID = 2
+	m.nutrientValues[nutrientValueKey{1, 1}] = 13
+	m.nutrientValues[nutrientValueKey{1, 2}] = 14
+	m.nutrientValues[nutrientValueKey{2, 2}] = 25
+
+	for i := 0; i < 2; i++ {
+		// syncing again must change nothing
+		if err := m.SyncNutrientCatalog(ctx, catalog.Nutrients); err != nil {
+			t.Fatalf("SyncNutrientCatalog: %v", err)
+		}
+	}
+
+	nutrients, err := m.ListNutrients(ctx)
+	if err != nil {
+		t.Fatalf("ListNutrients: %v", err)
+	}
+	if len(nutrients) != len(catalog.Nutrients)+1 {
+		t.Errorf("ListNutrients returned %d rows, want the %d catalog nutrients and the unmatched row", len(nutrients), len(catalog.Nutrients))
+	}
+	if nutrients[0] != (Nutrient{NutrientID: 1, Name: "Protein", Unit: catalog.UnitGram, Category: catalog.CategoryMacro}) {
+		t.Errorf("merged row = %+v", nutrients[0])
+	}
+	if nutrients[1] != (Nutrient{NutrientID: 3, Name: "Unobtainium"}) {
+		t.Errorf("unmatched row = %+v, want it left alone", nutrients[1])
+	}
+	if _, err := m.GetNutrientByName(ctx, "Unobtainium"); err == nil {
+		t.Error("GetNutrientByName found a nutrient outside the catalog")
+	}
+
+	// the value of the kept row wins when both rows had one
+	for ingredientID, want := range map[int64]float64{1: 13, 2: 25} {
+		ingredient, err := m.GetIngredient(ctx, ingredientID)
+		if err != nil {
+			t.Fatalf("GetIngredient: %v", err)
+		}
+		if len(ingredient.Nutrients) != 1 || ingredient.Nutrients[0].Name != "Protein" || ingredient.Nutrients[0].AmountPer100g != want {
+			t.Errorf("nutrients of %s = %+v, want %v g of Protein", ingredient.Name, ingredient.Nutrients, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort order")
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// cursor is the position after the last row of a page. Keys holds the sort
+// key of that row and ID breaks ties, so pages stay stable while rows are
+// inserted or deleted. Sort records the order the cursor was issued for.
+type cursor struct {
+	Sort string   `json:"s"`
+	Keys []string `json:"k,omitempty"`
+	ID   int64    `json:"id"`
+}
+
+func (c cursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses an opaque cursor issued for the given sort order. An
+// empty string decodes to nil, meaning the first page.
+func decodeCursor(encoded string, sort string, keys int) (*cursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || len(c.Keys) != keys {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// pageSize clamps a requested page size to [1, MaxPageSize].
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// Sort keys are stored in cursors in these fixed-width layouts so that they
+// compare the same way as strings and as SQL values.
+const (
+	cursorDateLayout = "2006-01-02"
+	cursorTimeLayout = "15:04:05.000000"
+)
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	issued := cursor{Sort: MealSortDateDesc, Keys: []string{"2023-11-20", "08:30:00.000000"}, ID: 42}
+	decoded, err := decodeCursor(issued.encode(), MealSortDateDesc, 2)
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if !reflect.DeepEqual(*decoded, issued) {
+		t.Errorf("decodeCursor = %+v, want %+v", *decoded, issued)
+	}
+
+	first, err := decodeCursor("", MealSortDate, 2)
+	if first != nil || err != nil {
+		t.Errorf("decodeCursor of an empty cursor = %v, %v, want nil, nil", first, err)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		sort    string
+		keys    int
+	}{
+		{"not base64", "!!!", MealSortDate, 2},
+		{"not JSON", base64.RawURLEncoding.EncodeToString([]byte("meal 42")), MealSortDate, 2},
+		{"other sort order", cursor{Sort: MealSortDateDesc, Keys: []string{"a", "b"}, ID: 1}.encode(), MealSortDate, 2},
+		{"missing keys", cursor{Sort: IngredientSortName, ID: 1}.encode(), IngredientSortName, 1},
+		{"keys for an ID sort", cursor{Sort: IngredientSortID, Keys: []string{"Oats"}, ID: 1}.encode(), IngredientSortID, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := decodeCursor(test.encoded, test.sort, test.keys); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor: got %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	for limit, want := range map[int]int{-1: DefaultPageSize, 0: DefaultPageSize, 1: 1, MaxPageSize: MaxPageSize, MaxPageSize + 1: MaxPageSize} {
+		if got := pageSize(limit); got != want {
+			t.Errorf("pageSize(%d) = %d, want %d", limit, got, want)
+		}
+	}
+}
+
+// collectMeals pages through ListMeals and returns the meal names in order.
+func collectMeals(t *testing.T, m *Memory, userID int64, filter MealFilter) []string {
+	t.Helper()
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("ListMeals keeps returning a next cursor")
+		}
+		page, err := m.ListMeals(context.Background(), userID, filter)
+		if err != nil {
+			t.Fatalf("ListMeals: %v", err)
+		}
+		if len(page.Meals) > filter.Limit {
+			t.Fatalf("ListMeals returned %d meals, limit is %d", len(page.Meals), filter.Limit)
+		}
+		for _, meal := range page.Meals {
+			names = append(names, meal.Name)
+		}
+		if page.NextCursor == "" {
+			return names
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+func TestListMealsPaging(t *testing.T) {
+	ctx := context.Background()
+	m, alice, bob := newTestMemory(t)
+
+	// two meals share a date and time so that the ID has to break the tie
+	times := []string{"2023-11-21T08:00:00Z", "2023-11-20T19:00:00Z", "2023-11-20T08:00:00Z", "2023-11-20T08:00:00Z", "2023-11-19T12:00:00Z"}
+	for i, raw := range times {
+		at, _ := time.Parse(time.RFC3339, raw)
+		if _, err := m.CreateMeal(ctx, &Meal{UserID: alice, Name: fmt.Sprintf("meal %d", i), Date: at, Time: at}); err != nil {
+			t.Fatalf("CreateMeal: %v", err)
+		}
+	}
+	if _, err := m.CreateMeal(ctx, &Meal{UserID: bob, Name: "not alice's"}); err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter MealFilter
+		want   []string
+	}{
+		{"newest first by default", MealFilter{Limit: 2}, []string{"meal 0", "meal 1", "meal 3", "meal 2", "meal 4"}},
+		{"oldest first", MealFilter{Sort: MealSortDate, Limit: 2}, []string{"meal 4", "meal 2", "meal 3", "meal 1", "meal 0"}},
+		{"one per page", MealFilter{Sort: MealSortDate, Limit: 1}, []string{"meal 4", "meal 2", "meal 3", "meal 1", "meal 0"}},
+		{
+			"date range",
+			MealFilter{From: time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC), Sort: MealSortDate, Limit: 2},
+			[]string{"meal 2", "meal 3", "meal 1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := collectMeals(t, m, alice, test.filter); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("meals = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// A cursor points after a row rather than at an offset, so deleting rows of
+// earlier pages neither skips nor repeats rows.
+func TestListMealsCursorSurvivesDeletes(t *testing.T) {
+	ctx := context.Background()
+	m, alice, _ := newTestMemory(t)
+	var mealIDs []int64
+	for day := 1; day <= 4; day++ {
+		at := time.Date(2023, 11, day, 12, 0, 0, 0, time.UTC)
+		mealID, err := m.CreateMeal(ctx, &Meal{UserID: alice, Name: fmt.Sprintf("day %d", day), Date: at, Time: at})
+		if err != nil {
+			t.Fatalf("CreateMeal: %v", err)
+		}
+		mealIDs = append(mealIDs, mealID)
+	}
+
+	first, err := m.ListMeals(ctx, alice, MealFilter{Sort: MealSortDate, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListMeals: %v", err)
+	}
+	for _, mealID := range mealIDs[:2] {
+		if _, err := m.DeleteMeal(ctx, alice, mealID, time.Time{}); err != nil {
+			t.Fatalf("DeleteMeal: %v", err)
+		}
+	}
+	second, err := m.ListMeals(ctx, alice, MealFilter{Sort: MealSortDate, Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("ListMeals: %v", err)
+	}
+	if len(second.Meals) != 2 || second.Meals[0].Name != "day 3" || second.Meals[1].Name != "day 4" || second.NextCursor != "" {
+		t.Errorf("second page = %+v", second)
+	}
+}
+
+func TestListMealsRejectsCursorOfAnotherSort(t *testing.T) {
+	ctx := context.Background()
+	m, alice, _ := newTestMemory(t)
+	for i := 0; i < 3; i++ {
+		if _, err := m.CreateMeal(ctx, &Meal{UserID: alice, Name: "meal"}); err != nil {
+			t.Fatalf("CreateMeal: %v", err)
+		}
+	}
+
+	page, err := m.ListMeals(ctx, alice, MealFilter{Sort: MealSortDate, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListMeals: %v", err)
+	}
+	if _, err := m.ListMeals(ctx, alice, MealFilter{Sort: MealSortDateDesc, Limit: 1, Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ListMeals with a cursor of another sort: got %v, want ErrInvalidCursor", err)
+	}
+	if _, err := m.ListMeals(ctx, alice, MealFilter{Sort: "name"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("ListMeals sorted by name: got %v, want ErrInvalidSort", err)
+	}
+}
+
+func TestListIngredientsPaging(t *testing.T) {
+	ctx := context.Background()
+	m, _, _ := newTestMemory(t)
+	for _, name := range []string{"Rye", "Oats", "oat milk", "Barley", "Rice"} {
+		createTestIngredient(t, m, name)
+	}
+	if _, err := m.SetIngredientNutrients(ctx, 1, []NutrientValue{{Name: "Fiber", AmountPer100g: 15}}); err != nil {
+		t.Fatalf("SetIngredientNutrients: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter IngredientFilter
+		want   []string
+	}{
+		{"by name", IngredientFilter{Limit: 2}, []string{"Barley", "Oats", "Rice", "Rye", "oat milk"}},
+		{"by name descending", IngredientFilter{Sort: IngredientSortNameDesc, Limit: 2}, []string{"oat milk", "Rye", "Rice", "Oats", "Barley"}},
+		{"by ID", IngredientFilter{Sort: IngredientSortID, Limit: 3}, []string{"Rye", "Oats", "oat milk", "Barley", "Rice"}},
+		{"by ID descending", IngredientFilter{Sort: IngredientSortIDDesc, Limit: 3}, []string{"Rice", "Barley", "oat milk", "Oats", "Rye"}},
+		{"name prefix ignores case", IngredientFilter{NamePrefix: "OAT", Limit: 1}, []string{"Oats", "oat milk"}},
+		{"has nutrient", IngredientFilter{HasNutrient: "Fiber", Limit: 1}, []string{"Rye"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			filter := test.filter
+			for pages := 0; ; pages++ {
+				if pages > 10 {
+					t.Fatal("ListIngredients keeps returning a next cursor")
+				}
+				page, err := m.ListIngredients(ctx, filter)
+				if err != nil {
+					t.Fatalf("ListIngredients: %v", err)
+				}
+				for _, ingredient := range page.Ingredients {
+					got = append(got, ingredient.Name)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				filter.Cursor = page.NextCursor
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ingredients = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,924 @@
+package store
+
+import (
+	"assignment2/catalog"
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory implementation of Store. It mirrors the schema
+// created by the migrations package, including its composite primary keys,
+// unique barcodes and ON DELETE CASCADE foreign keys, so it can stand in for
+// Postgres in tests and local demos.
+type Memory struct {
+	mu sync.RWMutex
+
+	meals           map[int64]*Meal
+	mealIngredients map[mealIngredientKey]float64
+	ingredients     map[int64]string
+	products        map[int64]product
+	nutrients       map[int64]*Nutrient
+	nutrientValues  map[nutrientValueKey]float64
+	users           map[int64]*User
+	goals           map[goalKey]*Goal
+	recipes         map[int64]*recipeRow
+	recipeLines     map[recipeIngredientKey]float64
+
+	nextMealID       int64
+	nextIngredientID int64
+	nextNutrientID   int64
+	nextUserID       int64
+}
+
+// mealIngredientKey is the primary key of Meal_Ingredients.
+type mealIngredientKey struct {
+	MealID       int64
+	IngredientID int64
+}
+
+// nutrientValueKey is the primary key of Nutrient_Values.
+type nutrientValueKey struct {
+	IngredientID int64
+	NutrientID   int64
+}
+
+// product holds the barcode and brand columns of an ingredient that has a
+// barcode.
+type product struct {
+	Barcode string
+	Brand   string
+}
+
+// goalKey is the primary key of Nutrition_Goals.
+type goalKey struct {
+	UserID     int64
+	NutrientID int64
+}
+
+// now matches the microsecond precision of Postgres timestamps.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		meals:           make(map[int64]*Meal),
+		mealIngredients: make(map[mealIngredientKey]float64),
+		ingredients:     make(map[int64]string),
+		products:        make(map[int64]product),
+		nutrients:       make(map[int64]*Nutrient),
+		nutrientValues:  make(map[nutrientValueKey]float64),
+		users:           make(map[int64]*User),
+		goals:           make(map[goalKey]*Goal),
+		recipes:         make(map[int64]*recipeRow),
+		recipeLines:     make(map[recipeIngredientKey]float64),
+	}
+}
+
+func (m *Memory) CreateMeal(ctx context.Context, meal *Meal) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[meal.UserID]; !ok {
+		return 0, fmt.Errorf("inserting into Meals table: user %d does not exist", meal.UserID)
+	}
+
+	// validate every line before writing anything so a failure behaves like
+	// a rolled back transaction
+	seen := make(map[int64]bool)
+	for _, ingredient := range meal.Ingredients {
+		if _, ok := m.ingredients[ingredient.IngredientID]; !ok {
+			return 0, ErrIngredientNotFound
+		}
+		if seen[ingredient.IngredientID] {
+			return 0, ErrMealIngredientExists
+		}
+		seen[ingredient.IngredientID] = true
+	}
+
+	m.nextMealID++
+	mealID := m.nextMealID
+	m.meals[mealID] = &Meal{MealID: mealID, UserID: meal.UserID, Name: meal.Name, Date: meal.Date, Time: meal.Time, UpdatedAt: now()}
+	for _, ingredient := range meal.Ingredients {
+		m.mealIngredients[mealIngredientKey{mealID, ingredient.IngredientID}] = ingredient.AmountInGrams
+	}
+	return mealID, nil
+}
+
+func (m *Memory) GetMeal(ctx context.Context, userID int64, mealID int64) (*Meal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.ownedMealLocked(userID, mealID)
+	if !ok {
+		return nil, ErrMealNotFound
+	}
+	return m.mealLocked(stored), nil
+}
+
+func (m *Memory) AddIngredientToMeal(ctx context.Context, userID int64, mealID int64, ingredient MealIngredient) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ownedMealLocked(userID, mealID); !ok {
+		return ErrMealNotFound
+	}
+	if _, ok := m.ingredients[ingredient.IngredientID]; !ok {
+		return ErrIngredientNotFound
+	}
+	key := mealIngredientKey{mealID, ingredient.IngredientID}
+	if _, ok := m.mealIngredients[key]; ok {
+		return ErrMealIngredientExists
+	}
+	m.mealIngredients[key] = ingredient.AmountInGrams
+	m.meals[mealID].UpdatedAt = now()
+	return nil
+}
+
+func (m *Memory) SetMealIngredient(ctx context.Context, userID int64, mealID int64, ingredient MealIngredient) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meal, ok := m.ownedMealLocked(userID, mealID)
+	if !ok {
+		return false, ErrMealNotFound
+	}
+	if _, ok := m.ingredients[ingredient.IngredientID]; !ok {
+		return false, ErrIngredientNotFound
+	}
+	key := mealIngredientKey{mealID, ingredient.IngredientID}
+	_, exists := m.mealIngredients[key]
+	m.mealIngredients[key] = ingredient.AmountInGrams
+	meal.UpdatedAt = now()
+	return !exists, nil
+}
+
+func (m *Memory) UpdateMeal(ctx context.Context, userID int64, mealID int64, update MealUpdate, ifUpdatedAt time.Time) (*Meal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meal, err := m.lockMealLocked(userID, mealID, ifUpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if update.Name != nil {
+		meal.Name = *update.Name
+	}
+	if update.DateTime != nil {
+		meal.Date = *update.DateTime
+		meal.Time = *update.DateTime
+	}
+	meal.UpdatedAt = now()
+	return m.mealLocked(meal), nil
+}
+
+func (m *Memory) RemoveIngredientFromMeal(ctx context.Context, userID int64, mealID int64, ingredientID int64, ifUpdatedAt time.Time) (*Meal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meal, err := m.lockMealLocked(userID, mealID, ifUpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	key := mealIngredientKey{mealID, ingredientID}
+	if _, ok := m.mealIngredients[key]; !ok {
+		return nil, ErrMealIngredientNotFound
+	}
+	delete(m.mealIngredients, key)
+	meal.UpdatedAt = now()
+	return m.mealLocked(meal), nil
+}
+
+func (m *Memory) DeleteMeal(ctx context.Context, userID int64, mealID int64, ifUpdatedAt time.Time) (*Meal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meal, err := m.lockMealLocked(userID, mealID, ifUpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	deleted := m.mealLocked(meal)
+	delete(m.meals, mealID)
+	for key := range m.mealIngredients {
+		if key.MealID == mealID {
+			delete(m.mealIngredients, key)
+		}
+	}
+	return deleted, nil
+}
+
+// lockMealLocked is the counterpart of the Postgres lockMeal helper. m.mu
+// must be held for writing.
+func (m *Memory) lockMealLocked(userID int64, mealID int64, ifUpdatedAt time.Time) (*Meal, error) {
+	meal, ok := m.ownedMealLocked(userID, mealID)
+	if !ok {
+		return nil, ErrMealNotFound
+	}
+	if !ifUpdatedAt.IsZero() && !meal.UpdatedAt.Equal(ifUpdatedAt) {
+		return nil, ErrMealModified
+	}
+	return meal, nil
+}
+
+// mealLocked returns a copy of the stored meal with its ingredient lines.
+// m.mu must be held.
+func (m *Memory) mealLocked(stored *Meal) *Meal {
+	meal := *stored
+	meal.Ingredients = m.mealIngredientsLocked(meal.MealID)
+	return &meal
+}
+
+// ownedMealLocked returns the meal if it exists and belongs to userID. m.mu
+// must be held.
+func (m *Memory) ownedMealLocked(userID int64, mealID int64) (*Meal, bool) {
+	meal, ok := m.meals[mealID]
+	if !ok || meal.UserID != userID {
+		return nil, false
+	}
+	return meal, true
+}
+
+// mealIngredientsLocked returns the lines of a meal ordered by ingredient ID.
+// m.mu must be held.
+func (m *Memory) mealIngredientsLocked(mealID int64) []MealIngredient {
+	var ingredients []MealIngredient
+	for key, amount := range m.mealIngredients {
+		if key.MealID != mealID {
+			continue
+		}
+		ingredients = append(ingredients, MealIngredient{
+			IngredientID:  key.IngredientID,
+			Name:          m.ingredients[key.IngredientID],
+			AmountInGrams: amount,
+		})
+	}
+	sort.Slice(ingredients, func(i, j int) bool {
+		return ingredients[i].IngredientID < ingredients[j].IngredientID
+	})
+	return ingredients
+}
+
+func (m *Memory) CreateIngredient(ctx context.Context, ingredient *Ingredient) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ingredientByNameLocked(ingredient.Name); ok {
+		return 0, ErrIngredientExists
+	}
+	if _, ok := m.ingredientByBarcodeLocked(ingredient.Barcode); ok {
+		return 0, ErrBarcodeExists
+	}
+	nutrientIDs, err := m.nutrientIDsLocked(ingredient.Nutrients)
+	if err != nil {
+		return 0, err
+	}
+
+	m.nextIngredientID++
+	ingredientID := m.nextIngredientID
+	m.ingredients[ingredientID] = ingredient.Name
+	m.setProductLocked(ingredientID, ingredient.Barcode, ingredient.Brand)
+	m.upsertNutrientValuesLocked(ingredientID, nutrientIDs, ingredient.Nutrients)
+	return ingredientID, nil
+}
+
+func (m *Memory) ReplaceIngredient(ctx context.Context, ingredient *Ingredient) (*Ingredient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ingredients[ingredient.IngredientID]; !ok {
+		return nil, ErrIngredientNotFound
+	}
+	if _, ok := m.recipes[ingredient.IngredientID]; ok {
+		return nil, ErrIngredientIsRecipe
+	}
+	if existingID, ok := m.ingredientByNameLocked(ingredient.Name); ok && existingID != ingredient.IngredientID {
+		return nil, ErrIngredientExists
+	}
+	if existingID, ok := m.ingredientByBarcodeLocked(ingredient.Barcode); ok && existingID != ingredient.IngredientID {
+		return nil, ErrBarcodeExists
+	}
+	nutrientIDs, err := m.nutrientIDsLocked(ingredient.Nutrients)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ingredients[ingredient.IngredientID] = ingredient.Name
+	m.setProductLocked(ingredient.IngredientID, ingredient.Barcode, ingredient.Brand)
+	for key := range m.nutrientValues {
+		if key.IngredientID == ingredient.IngredientID {
+			delete(m.nutrientValues, key)
+		}
+	}
+	m.upsertNutrientValuesLocked(ingredient.IngredientID, nutrientIDs, ingredient.Nutrients)
+	m.deriveRecipesUsingLocked(ingredient.IngredientID)
+	return m.ingredientLocked(ingredient.IngredientID), nil
+}
+
+func (m *Memory) SetIngredientNutrients(ctx context.Context, ingredientID int64, nutrients []NutrientValue) (*Ingredient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ingredients[ingredientID]; !ok {
+		return nil, ErrIngredientNotFound
+	}
+	if _, ok := m.recipes[ingredientID]; ok {
+		return nil, ErrIngredientIsRecipe
+	}
+	nutrientIDs, err := m.nutrientIDsLocked(nutrients)
+	if err != nil {
+		return nil, err
+	}
+	m.upsertNutrientValuesLocked(ingredientID, nutrientIDs, nutrients)
+	m.deriveRecipesUsingLocked(ingredientID)
+	return m.ingredientLocked(ingredientID), nil
+}
+
+// nutrientIDsLocked looks up the catalog nutrient of every value so that
+// writes can fail before anything is changed. m.mu must be held.
+func (m *Memory) nutrientIDsLocked(nutrients []NutrientValue) ([]int64, error) {
+	nutrientIDs := make([]int64, len(nutrients))
+	for i, nutrient := range nutrients {
+		stored, ok := m.nutrientByNameLocked(nutrient.Name)
+		if !ok {
+			return nil, ErrNutrientNotFound
+		}
+		nutrientIDs[i] = stored.NutrientID
+	}
+	return nutrientIDs, nil
+}
+
+// upsertNutrientValuesLocked stores the per 100 g amounts of an ingredient
+// using the IDs returned by nutrientIDsLocked. m.mu must be held.
+func (m *Memory) upsertNutrientValuesLocked(ingredientID int64, nutrientIDs []int64, nutrients []NutrientValue) {
+	for i, nutrient := range nutrients {
+		// rounded like the NUMERIC(10,2) column
+		m.nutrientValues[nutrientValueKey{ingredientID, nutrientIDs[i]}] = math.Round(nutrient.AmountPer100g*100) / 100
+	}
+}
+
+func (m *Memory) UpsertIngredients(ctx context.Context, ingredients []Ingredient) (*UpsertSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// resolve every nutrient first so that a failing batch changes nothing
+	nutrientIDs := make([][]int64, len(ingredients))
+	for i, ingredient := range ingredients {
+		ids, err := m.nutrientIDsLocked(ingredient.Nutrients)
+		if err != nil {
+			return nil, err
+		}
+		nutrientIDs[i] = ids
+	}
+
+	var summary UpsertSummary
+	for i, ingredient := range ingredients {
+		var ingredientID int64
+		var ok bool
+		if ingredient.Barcode != "" {
+			ingredientID, ok = m.ingredientByBarcodeLocked(ingredient.Barcode)
+		} else {
+			ingredientID, ok = m.ingredientByNameLocked(ingredient.Name)
+		}
+		if !ok {
+			if _, taken := m.ingredientByNameLocked(ingredient.Name); taken {
+				// a new product must not take the name of another ingredient
+				summary.Skipped++
+				continue
+			}
+			m.nextIngredientID++
+			m.ingredients[m.nextIngredientID] = ingredient.Name
+			m.setProductLocked(m.nextIngredientID, ingredient.Barcode, ingredient.Brand)
+			m.upsertNutrientValuesLocked(m.nextIngredientID, nutrientIDs[i], ingredient.Nutrients)
+			summary.Created++
+			continue
+		}
+		if _, ok := m.recipes[ingredientID]; ok {
+			summary.Skipped++
+			continue
+		}
+
+		before := m.ingredientLocked(ingredientID)
+		m.upsertNutrientValuesLocked(ingredientID, nutrientIDs[i], ingredient.Nutrients)
+		if ingredient.Brand != "" {
+			m.setProductLocked(ingredientID, m.products[ingredientID].Barcode, ingredient.Brand)
+		}
+		if reflect.DeepEqual(before, m.ingredientLocked(ingredientID)) {
+			summary.Unchanged++
+			continue
+		}
+		m.deriveRecipesUsingLocked(ingredientID)
+		summary.Updated++
+	}
+	return &summary, nil
+}
+
+func (m *Memory) GetIngredient(ctx context.Context, ingredientID int64) (*Ingredient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.ingredients[ingredientID]; !ok {
+		return nil, ErrIngredientNotFound
+	}
+	return m.ingredientLocked(ingredientID), nil
+}
+
+func (m *Memory) DeleteIngredient(ctx context.Context, ingredientID int64) (*Ingredient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ingredients[ingredientID]; !ok {
+		return nil, ErrIngredientNotFound
+	}
+	if m.usedByRecipeLocked(ingredientID) {
+		return nil, ErrIngredientInUse
+	}
+	deleted := m.ingredientLocked(ingredientID)
+	deleted.Nutrients = nil
+	m.deleteIngredientLocked(ingredientID)
+	return deleted, nil
+}
+
+func (m *Memory) GetIngredientByBarcode(ctx context.Context, barcode string) (*Ingredient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ingredientID, ok := m.ingredientByBarcodeLocked(barcode)
+	if !ok {
+		return nil, ErrIngredientNotFound
+	}
+	return m.ingredientLocked(ingredientID), nil
+}
+
+// ingredientLocked returns a stored ingredient with its nutrient values.
+// m.mu must be held.
+func (m *Memory) ingredientLocked(ingredientID int64) *Ingredient {
+	product := m.products[ingredientID]
+	return &Ingredient{
+		IngredientID: ingredientID,
+		Name:         m.ingredients[ingredientID],
+		Barcode:      product.Barcode,
+		Brand:        product.Brand,
+		Nutrients:    m.nutrientValuesLocked(ingredientID),
+	}
+}
+
+// setProductLocked stores the barcode and brand of an ingredient, dropping
+// the row once both are empty. m.mu must be held.
+func (m *Memory) setProductLocked(ingredientID int64, barcode string, brand string) {
+	if barcode == "" && brand == "" {
+		delete(m.products, ingredientID)
+		return
+	}
+	m.products[ingredientID] = product{Barcode: barcode, Brand: brand}
+}
+
+func (m *Memory) ingredientByBarcodeLocked(barcode string) (int64, bool) {
+	if barcode == "" {
+		return 0, false
+	}
+	for ingredientID, product := range m.products {
+		if product.Barcode == barcode {
+			return ingredientID, true
+		}
+	}
+	return 0, false
+}
+
+// deleteIngredientLocked removes an ingredient and cascades to the
+// Nutrient_Values, Meal_Ingredients and recipe rows that reference it. m.mu
+// must be held.
+func (m *Memory) deleteIngredientLocked(ingredientID int64) {
+	delete(m.ingredients, ingredientID)
+	delete(m.products, ingredientID)
+	delete(m.recipes, ingredientID)
+	for key := range m.recipeLines {
+		if key.RecipeID == ingredientID {
+			delete(m.recipeLines, key)
+		}
+	}
+	for key := range m.nutrientValues {
+		if key.IngredientID == ingredientID {
+			delete(m.nutrientValues, key)
+		}
+	}
+	for key := range m.mealIngredients {
+		if key.IngredientID == ingredientID {
+			delete(m.mealIngredients, key)
+		}
+	}
+}
+
+func (m *Memory) ingredientByNameLocked(name string) (int64, bool) {
+	for ingredientID, ingredientName := range m.ingredients {
+		if ingredientName == name {
+			return ingredientID, true
+		}
+	}
+	return 0, false
+}
+
+// nutrientValuesLocked returns the nutrient values of an ingredient ordered
+// by nutrient name. m.mu must be held.
+func (m *Memory) nutrientValuesLocked(ingredientID int64) []NutrientValue {
+	var nutrients []NutrientValue
+	for key, amount := range m.nutrientValues {
+		if key.IngredientID != ingredientID {
+			continue
+		}
+		nutrient := m.nutrients[key.NutrientID]
+		nutrients = append(nutrients, NutrientValue{
+			NutrientID:    key.NutrientID,
+			Name:          nutrient.Name,
+			Unit:          nutrient.Unit,
+			AmountPer100g: amount,
+		})
+	}
+	sort.Slice(nutrients, func(i, j int) bool {
+		return nutrients[i].Name < nutrients[j].Name
+	})
+	return nutrients
+}
+
+func (m *Memory) GetNutrientByName(ctx context.Context, name string) (*Nutrient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nutrient, ok := m.nutrientByNameLocked(name)
+	if !ok {
+		return nil, ErrNutrientNotFound
+	}
+	copied := *nutrient
+	return &copied, nil
+}
+
+func (m *Memory) ListNutrients(ctx context.Context) ([]Nutrient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.nutrientsLocked(), nil
+}
+
+// nutrientsLocked returns every nutrient ordered by ID. m.mu must be held.
+func (m *Memory) nutrientsLocked() []Nutrient {
+	var nutrients []Nutrient
+	for _, nutrient := range m.nutrients {
+		nutrients = append(nutrients, *nutrient)
+	}
+	sort.Slice(nutrients, func(i, j int) bool {
+		return nutrients[i].NutrientID < nutrients[j].NutrientID
+	})
+	return nutrients
+}
+
+func (m *Memory) SyncNutrientCatalog(ctx context.Context, nutrients []catalog.Nutrient) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, plan := range planCatalogSync(m.nutrientsLocked(), nutrients) {
+		if plan.keep == 0 {
+			m.nextNutrientID++
+			plan.keep = m.nextNutrientID
+			m.nutrients[plan.keep] = &Nutrient{NutrientID: plan.keep}
+		}
+		for _, nutrientID := range plan.merge {
+			for key, amount := range m.nutrientValues {
+				if key.NutrientID != nutrientID {
+					continue
+				}
+				merged := nutrientValueKey{key.IngredientID, plan.keep}
+				if _, ok := m.nutrientValues[merged]; !ok {
+					m.nutrientValues[merged] = amount
+				}
+				delete(m.nutrientValues, key)
+			}
+			delete(m.nutrients, nutrientID)
+		}
+		*m.nutrients[plan.keep] = Nutrient{NutrientID: plan.keep, Name: plan.nutrient.Name, Unit: plan.nutrient.Unit, Category: plan.nutrient.Category}
+	}
+	return nil
+}
+
+// nutrientByNameLocked only finds catalog nutrients, like its Postgres
+// counterpart. m.mu must be held.
+func (m *Memory) nutrientByNameLocked(name string) (*Nutrient, bool) {
+	for _, nutrient := range m.nutrients {
+		if nutrient.Name == name && nutrient.Unit != "" {
+			return nutrient, true
+		}
+	}
+	return nil, false
+}
+
+func (m *Memory) CreateUser(ctx context.Context, user *User) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if existing.Username == user.Username || existing.Email == user.Email {
+			return 0, ErrUserExists
+		}
+	}
+
+	m.nextUserID++
+	stored := *user
+	stored.UserID = m.nextUserID
+	stored.CreatedAt = now()
+	m.users[stored.UserID] = &stored
+	return stored.UserID, nil
+}
+
+func (m *Memory) GetUser(ctx context.Context, userID int64) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (m *Memory) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Username == username {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (m *Memory) ListMeals(ctx context.Context, userID int64, filter MealFilter) (*MealPage, error) {
+	sortOrder := filter.Sort
+	if sortOrder == "" {
+		sortOrder = MealSortDateDesc
+	}
+	if sortOrder != MealSortDate && sortOrder != MealSortDateDesc {
+		return nil, ErrInvalidSort
+	}
+	after, err := decodeCursor(filter.Cursor, sortOrder, 2)
+	if err != nil {
+		return nil, err
+	}
+	from := filter.From.Format(cursorDateLayout)
+	to := filter.To.Format(cursorDateLayout)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []keyedRow
+	for _, meal := range m.meals {
+		date := meal.Date.Format(cursorDateLayout)
+		if meal.UserID != userID || (!filter.From.IsZero() && date < from) || (!filter.To.IsZero() && date > to) {
+			continue
+		}
+		rows = append(rows, keyedRow{keys: mealSortKeys(meal), id: meal.MealID})
+	}
+
+	var page MealPage
+	rows, page.NextCursor = paginate(rows, after, sortOrder, sortOrder == MealSortDateDesc, pageSize(filter.Limit))
+	for _, row := range rows {
+		page.Meals = append(page.Meals, *m.mealLocked(m.meals[row.id]))
+	}
+	return &page, nil
+}
+
+func (m *Memory) ListIngredients(ctx context.Context, filter IngredientFilter) (*IngredientPage, error) {
+	sortOrder := filter.Sort
+	if sortOrder == "" {
+		sortOrder = IngredientSortName
+	}
+	byName := sortOrder == IngredientSortName || sortOrder == IngredientSortNameDesc
+	if !byName && sortOrder != IngredientSortID && sortOrder != IngredientSortIDDesc {
+		return nil, ErrInvalidSort
+	}
+	keys := 0
+	if byName {
+		keys = 1
+	}
+	after, err := decodeCursor(filter.Cursor, sortOrder, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []keyedRow
+	for ingredientID, name := range m.ingredients {
+		if !strings.HasPrefix(strings.ToLower(name), strings.ToLower(filter.NamePrefix)) {
+			continue
+		}
+		if filter.HasNutrient != "" && !m.hasNutrientLocked(ingredientID, filter.HasNutrient) {
+			continue
+		}
+		row := keyedRow{id: ingredientID}
+		if byName {
+			row.keys = []string{name}
+		}
+		rows = append(rows, row)
+	}
+
+	var page IngredientPage
+	descending := sortOrder == IngredientSortNameDesc || sortOrder == IngredientSortIDDesc
+	rows, page.NextCursor = paginate(rows, after, sortOrder, descending, pageSize(filter.Limit))
+	for _, row := range rows {
+		page.Ingredients = append(page.Ingredients, *m.ingredientLocked(row.id))
+	}
+	return &page, nil
+}
+
+func (m *Memory) hasNutrientLocked(ingredientID int64, nutrientName string) bool {
+	for key := range m.nutrientValues {
+		if key.IngredientID == ingredientID && strings.EqualFold(m.nutrients[key.NutrientID].Name, nutrientName) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyedRow is a row reduced to its sort keys and ID for pagination.
+type keyedRow struct {
+	keys []string
+	id   int64
+}
+
+func (r keyedRow) compare(keys []string, id int64) int {
+	for i := range r.keys {
+		if c := strings.Compare(r.keys[i], keys[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case r.id < id:
+		return -1
+	case r.id > id:
+		return 1
+	}
+	return 0
+}
+
+// paginate sorts rows, skips everything up to and including after and returns
+// at most limit rows plus the cursor of the next page, if any. It is the
+// in-memory counterpart of the keyset queries in the Postgres store.
+func paginate(rows []keyedRow, after *cursor, sortOrder string, descending bool, limit int) ([]keyedRow, string) {
+	sort.Slice(rows, func(i, j int) bool {
+		c := rows[i].compare(rows[j].keys, rows[j].id)
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	start := 0
+	if after != nil {
+		for start < len(rows) {
+			c := rows[start].compare(after.Keys, after.ID)
+			if (descending && c < 0) || (!descending && c > 0) {
+				break
+			}
+			start++
+		}
+	}
+	rows = rows[start:]
+
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	last := rows[limit-1]
+	return rows, cursor{Sort: sortOrder, Keys: last.keys, ID: last.id}.encode()
+}
+
+func (m *Memory) GetMealNutrition(ctx context.Context, userID int64, mealID int64) (*MealNutrition, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	meal, ok := m.ownedMealLocked(userID, mealID)
+	if !ok {
+		return nil, ErrMealNotFound
+	}
+	return m.mealNutritionLocked(meal), nil
+}
+
+// mealNutritionLocked computes the nutrient breakdown of a meal. m.mu must be
+// held.
+func (m *Memory) mealNutritionLocked(meal *Meal) *MealNutrition {
+	nutrition := &MealNutrition{MealID: meal.MealID}
+	totals := make(map[int64]*NutrientTotal)
+	for _, line := range m.mealIngredientsLocked(meal.MealID) {
+		ingredient := IngredientNutrition{IngredientID: line.IngredientID, Name: line.Name, AmountInGrams: line.AmountInGrams}
+		for _, value := range m.nutrientValuesLocked(line.IngredientID) {
+			amount := value.AmountPer100g * line.AmountInGrams / 100
+			ingredient.Nutrients = append(ingredient.Nutrients, NutrientTotal{NutrientID: value.NutrientID, Name: value.Name, Unit: value.Unit, Amount: amount})
+			addToTotals(totals, value, amount)
+		}
+		nutrition.Ingredients = append(nutrition.Ingredients, ingredient)
+	}
+	nutrition.Totals = sortedTotals(totals)
+	return nutrition
+}
+
+func addToTotals(totals map[int64]*NutrientTotal, value NutrientValue, amount float64) {
+	if total, ok := totals[value.NutrientID]; ok {
+		total.Amount += amount
+		return
+	}
+	totals[value.NutrientID] = &NutrientTotal{NutrientID: value.NutrientID, Name: value.Name, Unit: value.Unit, Amount: amount}
+}
+
+// sortedTotals returns the totals ordered by nutrient name.
+func sortedTotals(totals map[int64]*NutrientTotal) []NutrientTotal {
+	var sorted []NutrientTotal
+	for _, total := range totals {
+		sorted = append(sorted, *total)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func (m *Memory) ListMealNutrition(ctx context.Context, userID int64, from time.Time, to time.Time) ([]MealNutritionSummary, error) {
+	first, last := from.Format(cursorDateLayout), to.Format(cursorDateLayout)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []keyedRow
+	for _, meal := range m.meals {
+		date := meal.Date.Format(cursorDateLayout)
+		if meal.UserID == userID && date >= first && date <= last {
+			rows = append(rows, keyedRow{keys: mealSortKeys(meal), id: meal.MealID})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].compare(rows[j].keys, rows[j].id) < 0
+	})
+
+	var summaries []MealNutritionSummary
+	for _, row := range rows {
+		meal := m.meals[row.id]
+		totals := make(map[int64]*NutrientTotal)
+		for key, quantity := range m.mealIngredients {
+			if key.MealID != meal.MealID {
+				continue
+			}
+			for _, value := range m.nutrientValuesLocked(key.IngredientID) {
+				addToTotals(totals, value, value.AmountPer100g*quantity/100)
+			}
+		}
+		summaries = append(summaries, MealNutritionSummary{
+			MealID: meal.MealID,
+			Name:   meal.Name,
+			Date:   meal.Date,
+			Time:   meal.Time,
+			Totals: sortedTotals(totals),
+		})
+	}
+	return summaries, nil
+}
+
+// ExportMeals copies the meals of the range under the read lock and calls fn
+// once the lock is released, so that a slow consumer does not block writers.
+func (m *Memory) ExportMeals(ctx context.Context, userID int64, from time.Time, to time.Time, fn func(*MealExport) error) error {
+	first, last := from.Format(cursorDateLayout), to.Format(cursorDateLayout)
+
+	m.mu.RLock()
+	var rows []keyedRow
+	for _, meal := range m.meals {
+		date := meal.Date.Format(cursorDateLayout)
+		if meal.UserID == userID && date >= first && date <= last {
+			rows = append(rows, keyedRow{keys: mealSortKeys(meal), id: meal.MealID})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].compare(rows[j].keys, rows[j].id) < 0
+	})
+	exports := make([]*MealExport, len(rows))
+	for i, row := range rows {
+		meal := m.meals[row.id]
+		exports[i] = &MealExport{Meal: *m.mealLocked(meal), Nutrition: *m.mealNutritionLocked(meal)}
+	}
+	m.mu.RUnlock()
+
+	for _, export := range exports {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+func (m *Memory) ListGoals(ctx context.Context, userID int64) ([]Goal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var goals []Goal
+	for key, goal := range m.goals {
+		if key.UserID == userID {
+			goals = append(goals, m.goalLocked(goal))
+		}
+	}
+	sort.Slice(goals, func(i, j int) bool {
+		return goals[i].Name < goals[j].Name
+	})
+	return goals, nil
+}
+
+func (m *Memory) SetGoal(ctx context.Context, userID int64, goal Goal) (*Goal, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nutrient, ok := m.nutrientByNameLocked(goal.Name)
+	if !ok {
+		return nil, false, ErrNutrientNotFound
+	}
+
+	key := goalKey{UserID: userID, NutrientID: nutrient.NutrientID}
+	_, exists := m.goals[key]
+	stored := &Goal{NutrientID: nutrient.NutrientID, Min: roundAmount(goal.Min), Max: roundAmount(goal.Max), UpdatedAt: now()}
+	m.goals[key] = stored
+	result := m.goalLocked(stored)
+	return &result, !exists, nil
+}
+
+func (m *Memory) DeleteGoal(ctx context.Context, userID int64, nutrientName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nutrient, ok := m.nutrientByNameLocked(nutrientName)
+	if !ok {
+		return ErrGoalNotFound
+	}
+	key := goalKey{UserID: userID, NutrientID: nutrient.NutrientID}
+	if _, ok := m.goals[key]; !ok {
+		return ErrGoalNotFound
+	}
+	delete(m.goals, key)
+	return nil
+}
+
+// goalLocked returns a copy of a stored goal with its nutrient filled in.
+// m.mu must be held.
+func (m *Memory) goalLocked(stored *Goal) Goal {
+	nutrient := m.nutrients[stored.NutrientID]
+	return Goal{
+		NutrientID: stored.NutrientID,
+		Name:       nutrient.Name,
+		Unit:       nutrient.Unit,
+		Min:        copyFloat(stored.Min),
+		Max:        copyFloat(stored.Max),
+		UpdatedAt:  stored.UpdatedAt,
+	}
+}
+
+// roundAmount copies an optional amount rounded like the NUMERIC(10,2)
+// columns.
+func roundAmount(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	rounded := math.Round(*f*100) / 100
+	return &rounded
+}
+
+func copyFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	copied := *f
+	return &copied
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// recipeRow holds the Recipes columns of a recipe; its name lives in
+// m.ingredients.
+type recipeRow struct {
+	YieldInGrams float64
+	UpdatedAt    time.Time
+}
+
+// recipeIngredientKey is the primary key of Recipe_Ingredients.
+type recipeIngredientKey struct {
+	RecipeID     int64
+	IngredientID int64
+}
+
+func (m *Memory) CreateRecipe(ctx context.Context, recipe *Recipe) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ingredientByNameLocked(recipe.Name); ok {
+		return 0, ErrIngredientExists
+	}
+	if err := m.checkRecipeIngredientsLocked(recipe.Ingredients); err != nil {
+		return 0, err
+	}
+
+	m.nextIngredientID++
+	recipeID := m.nextIngredientID
+	m.ingredients[recipeID] = recipe.Name
+	m.recipes[recipeID] = &recipeRow{YieldInGrams: recipe.YieldInGrams, UpdatedAt: now()}
+	for _, ingredient := range recipe.Ingredients {
+		m.recipeLines[recipeIngredientKey{recipeID, ingredient.IngredientID}] = ingredient.AmountInGrams
+	}
+	m.deriveRecipeNutrientsLocked(recipeID)
+	return recipeID, nil
+}
+
+func (m *Memory) GetRecipe(ctx context.Context, recipeID int64) (*Recipe, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.recipeLocked(recipeID)
+}
+
+func (m *Memory) ReplaceRecipe(ctx context.Context, recipe *Recipe) (*Recipe, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row, ok := m.recipes[recipe.RecipeID]
+	if !ok {
+		return nil, ErrRecipeNotFound
+	}
+	if existingID, ok := m.ingredientByNameLocked(recipe.Name); ok && existingID != recipe.RecipeID {
+		return nil, ErrIngredientExists
+	}
+	if err := m.checkRecipeIngredientsLocked(recipe.Ingredients); err != nil {
+		return nil, err
+	}
+
+	m.ingredients[recipe.RecipeID] = recipe.Name
+	row.YieldInGrams = recipe.YieldInGrams
+	row.UpdatedAt = now()
+	for key := range m.recipeLines {
+		if key.RecipeID == recipe.RecipeID {
+			delete(m.recipeLines, key)
+		}
+	}
+	for _, ingredient := range recipe.Ingredients {
+		m.recipeLines[recipeIngredientKey{recipe.RecipeID, ingredient.IngredientID}] = ingredient.AmountInGrams
+	}
+	m.deriveRecipeNutrientsLocked(recipe.RecipeID)
+	return m.recipeLocked(recipe.RecipeID)
+}
+
+func (m *Memory) DeleteRecipe(ctx context.Context, recipeID int64) (*Recipe, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recipe, err := m.recipeLocked(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	m.deleteIngredientLocked(recipeID)
+	return recipe, nil
+}
+
+// recipeLocked returns a copy of the recipe with its lines and nutrient
+// values. m.mu must be held.
+func (m *Memory) recipeLocked(recipeID int64) (*Recipe, error) {
+	row, ok := m.recipes[recipeID]
+	if !ok {
+		return nil, ErrRecipeNotFound
+	}
+
+	recipe := &Recipe{
+		RecipeID:     recipeID,
+		Name:         m.ingredients[recipeID],
+		YieldInGrams: row.YieldInGrams,
+		Nutrients:    m.nutrientValuesLocked(recipeID),
+		UpdatedAt:    row.UpdatedAt,
+	}
+	for key, quantity := range m.recipeLines {
+		if key.RecipeID == recipeID {
+			recipe.Ingredients = append(recipe.Ingredients, RecipeIngredient{IngredientID: key.IngredientID, Name: m.ingredients[key.IngredientID], AmountInGrams: quantity})
+		}
+	}
+	sort.Slice(recipe.Ingredients, func(i, j int) bool {
+		return recipe.Ingredients[i].Name < recipe.Ingredients[j].Name
+	})
+	return recipe, nil
+}
+
+// checkRecipeIngredientsLocked validates recipe lines before anything is
+// written. m.mu must be held.
+func (m *Memory) checkRecipeIngredientsLocked(ingredients []RecipeIngredient) error {
+	seen := make(map[int64]bool)
+	for _, ingredient := range ingredients {
+		if _, ok := m.ingredients[ingredient.IngredientID]; !ok {
+			return ErrIngredientNotFound
+		}
+		if _, ok := m.recipes[ingredient.IngredientID]; ok {
+			return ErrNestedRecipe
+		}
+		if seen[ingredient.IngredientID] {
+			return ErrRecipeIngredientExists
+		}
+		seen[ingredient.IngredientID] = true
+	}
+	return nil
+}
+
+// deriveRecipeNutrientsLocked replaces the nutrient values of a recipe with
+// the sum of its ingredients' nutrients scaled to 100 g of the cooked yield.
+// m.mu must be held.
+func (m *Memory) deriveRecipeNutrientsLocked(recipeID int64) {
+	for key := range m.nutrientValues {
+		if key.IngredientID == recipeID {
+			delete(m.nutrientValues, key)
+		}
+	}
+
+	totals := make(map[int64]float64)
+	for key, quantity := range m.recipeLines {
+		if key.RecipeID != recipeID {
+			continue
+		}
+		for _, value := range m.nutrientValuesLocked(key.IngredientID) {
+			totals[value.NutrientID] += value.AmountPer100g * quantity / 100
+		}
+	}
+
+	yield := m.recipes[recipeID].YieldInGrams
+	var nutrientIDs []int64
+	var values []NutrientValue
+	for nutrientID, total := range totals {
+		nutrientIDs = append(nutrientIDs, nutrientID)
+		values = append(values, NutrientValue{AmountPer100g: total * 100 / yield})
+	}
+	m.upsertNutrientValuesLocked(recipeID, nutrientIDs, values)
+}
+
+// deriveRecipesUsingLocked derives the nutrient values of every recipe
+// containing the ingredient again. m.mu must be held.
+func (m *Memory) deriveRecipesUsingLocked(ingredientID int64) {
+	for key := range m.recipeLines {
+		if key.IngredientID == ingredientID {
+			m.deriveRecipeNutrientsLocked(key.RecipeID)
+		}
+	}
+}
+
+func (m *Memory) usedByRecipeLocked(ingredientID int64) bool {
+	for key := range m.recipeLines {
+		if key.IngredientID == ingredientID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,418 @@
+package store
+
+import (
+	"assignment2/catalog"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestMemory returns a Memory seeded with the nutrient catalog and two
+// users.
+func newTestMemory(t *testing.T) (*Memory, int64, int64) {
+	t.Helper()
+	ctx := context.Background()
+	m := NewMemory()
+	if err := m.SyncNutrientCatalog(ctx, catalog.Nutrients); err != nil {
+		t.Fatalf("SyncNutrientCatalog: %v", err)
+	}
+	alice, err := m.CreateUser(ctx, &User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	bob, err := m.CreateUser(ctx, &User{Username: "bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return m, alice, bob
+}
+
+func createTestIngredient(t *testing.T, m *Memory, name string, nutrients ...NutrientValue) int64 {
+	t.Helper()
+	ingredientID, err := m.CreateIngredient(context.Background(), &Ingredient{Name: name, Nutrients: nutrients})
+	if err != nil {
+		t.Fatalf("CreateIngredient(%q): %v", name, err)
+	}
+	return ingredientID
+}
+
+func TestMemoryMealLifecycle(t *testing.T) {
+	ctx := context.Background()
+	m, alice, _ := newTestMemory(t)
+	oats := createTestIngredient(t, m, "Oats")
+	milk := createTestIngredient(t, m, "Milk")
+
+	eatenAt := time.Date(2023, 11, 20, 8, 30, 0, 0, time.UTC)
+	mealID, err := m.CreateMeal(ctx, &Meal{
+		UserID:      alice,
+		Name:        "Breakfast",
+		Date:        eatenAt,
+		Time:        eatenAt,
+		Ingredients: []MealIngredient{{IngredientID: oats, AmountInGrams: 50}},
+	})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+
+	if err := m.AddIngredientToMeal(ctx, alice, mealID, MealIngredient{IngredientID: milk, AmountInGrams: 200}); err != nil {
+		t.Fatalf("AddIngredientToMeal: %v", err)
+	}
+	err = m.AddIngredientToMeal(ctx, alice, mealID, MealIngredient{IngredientID: milk, AmountInGrams: 100})
+	if !errors.Is(err, ErrMealIngredientExists) {
+		t.Fatalf("AddIngredientToMeal twice: got %v, want ErrMealIngredientExists", err)
+	}
+	created, err := m.SetMealIngredient(ctx, alice, mealID, MealIngredient{IngredientID: milk, AmountInGrams: 250})
+	if err != nil || created {
+		t.Fatalf("SetMealIngredient on an existing line: got created=%v err=%v", created, err)
+	}
+
+	meal, err := m.GetMeal(ctx, alice, mealID)
+	if err != nil {
+		t.Fatalf("GetMeal: %v", err)
+	}
+	want := []MealIngredient{
+		{IngredientID: oats, Name: "Oats", AmountInGrams: 50},
+		{IngredientID: milk, Name: "Milk", AmountInGrams: 250},
+	}
+	if len(meal.Ingredients) != len(want) {
+		t.Fatalf("GetMeal ingredients = %+v, want %+v", meal.Ingredients, want)
+	}
+	for i := range want {
+		if meal.Ingredients[i] != want[i] {
+			t.Errorf("ingredient %d = %+v, want %+v", i, meal.Ingredients[i], want[i])
+		}
+	}
+
+	name := "Late breakfast"
+	updated, err := m.UpdateMeal(ctx, alice, mealID, MealUpdate{Name: &name}, meal.UpdatedAt)
+	if err != nil {
+		t.Fatalf("UpdateMeal: %v", err)
+	}
+	if updated.Name != name {
+		t.Errorf("UpdateMeal name = %q, want %q", updated.Name, name)
+	}
+	if _, err := m.UpdateMeal(ctx, alice, mealID, MealUpdate{Name: &name}, meal.UpdatedAt.Add(-time.Second)); !errors.Is(err, ErrMealModified) {
+		t.Errorf("UpdateMeal with a stale version: got %v, want ErrMealModified", err)
+	}
+
+	if _, err := m.RemoveIngredientFromMeal(ctx, alice, mealID, oats, time.Time{}); err != nil {
+		t.Fatalf("RemoveIngredientFromMeal: %v", err)
+	}
+	if _, err := m.RemoveIngredientFromMeal(ctx, alice, mealID, oats, time.Time{}); !errors.Is(err, ErrMealIngredientNotFound) {
+		t.Errorf("RemoveIngredientFromMeal twice: got %v, want ErrMealIngredientNotFound", err)
+	}
+
+	if _, err := m.DeleteMeal(ctx, alice, mealID, time.Time{}); err != nil {
+		t.Fatalf("DeleteMeal: %v", err)
+	}
+	if _, err := m.GetMeal(ctx, alice, mealID); !errors.Is(err, ErrMealNotFound) {
+		t.Errorf("GetMeal after DeleteMeal: got %v, want ErrMealNotFound", err)
+	}
+}
+
+func TestMemoryCreateMealRejectsBadLines(t *testing.T) {
+	ctx := context.Background()
+	m, alice, _ := newTestMemory(t)
+	oats := createTestIngredient(t, m, "Oats")
+
+	tests := []struct {
+		name        string
+		ingredients []MealIngredient
+		want        error
+	}{
+		{"unknown ingredient", []MealIngredient{{IngredientID: oats + 100, AmountInGrams: 10}}, ErrIngredientNotFound},
+		{"duplicate ingredient", []MealIngredient{{IngredientID: oats, AmountInGrams: 10}, {IngredientID: oats, AmountInGrams: 20}}, ErrMealIngredientExists},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := m.CreateMeal(ctx, &Meal{UserID: alice, Name: "Lunch", Ingredients: test.ingredients})
+			if !errors.Is(err, test.want) {
+				t.Fatalf("CreateMeal: got %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	// a failed insert must not leave a meal behind
+	page, err := m.ListMeals(ctx, alice, MealFilter{})
+	if err != nil {
+		t.Fatalf("ListMeals: %v", err)
+	}
+	if len(page.Meals) != 0 {
+		t.Errorf("ListMeals after failed inserts = %d meals, want 0", len(page.Meals))
+	}
+}
+
+func TestMemoryMealsAreScopedToTheirOwner(t *testing.T) {
+	ctx := context.Background()
+	m, alice, bob := newTestMemory(t)
+	oats := createTestIngredient(t, m, "Oats")
+
+	mealID, err := m.CreateMeal(ctx, &Meal{UserID: alice, Name: "Breakfast"})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+
+	if _, err := m.GetMeal(ctx, bob, mealID); !errors.Is(err, ErrMealNotFound) {
+		t.Errorf("GetMeal by another user: got %v, want ErrMealNotFound", err)
+	}
+	if err := m.AddIngredientToMeal(ctx, bob, mealID, MealIngredient{IngredientID: oats, AmountInGrams: 10}); !errors.Is(err, ErrMealNotFound) {
+		t.Errorf("AddIngredientToMeal by another user: got %v, want ErrMealNotFound", err)
+	}
+	if _, err := m.DeleteMeal(ctx, bob, mealID, time.Time{}); !errors.Is(err, ErrMealNotFound) {
+		t.Errorf("DeleteMeal by another user: got %v, want ErrMealNotFound", err)
+	}
+	page, err := m.ListMeals(ctx, bob, MealFilter{})
+	if err != nil {
+		t.Fatalf("ListMeals: %v", err)
+	}
+	if len(page.Meals) != 0 {
+		t.Errorf("ListMeals of another user = %d meals, want 0", len(page.Meals))
+	}
+
+	if _, err := m.GetMeal(ctx, alice, mealID); err != nil {
+		t.Errorf("GetMeal by the owner: %v", err)
+	}
+}
+
+func TestMemoryIngredientLifecycle(t *testing.T) {
+	ctx := context.Background()
+	m, alice, _ := newTestMemory(t)
+
+	ingredientID := createTestIngredient(t, m, "Oats",
+		NutrientValue{Name: "Protein", AmountPer100g: 13.154},
+		NutrientValue{Name: "Energy", AmountPer100g: 379},
+	)
+	if _, err := m.CreateIngredient(ctx, &Ingredient{Name: "Oats"}); !errors.Is(err, ErrIngredientExists) {
+		t.Errorf("CreateIngredient with a taken name: got %v, want ErrIngredientExists", err)
+	}
+	if _, err := m.CreateIngredient(ctx, &Ingredient{Name: "Rye", Nutrients: []NutrientValue{{Name: "Unobtainium"}}}); !errors.Is(err, ErrNutrientNotFound) {
+		t.Errorf("CreateIngredient with an unknown nutrient: got %v, want ErrNutrientNotFound", err)
+	}
+
+	ingredient, err := m.GetIngredient(ctx, ingredientID)
+	if err != nil {
+		t.Fatalf("GetIngredient: %v", err)
+	}
+	// ordered by name and rounded like NUMERIC(10,2)
+	if len(ingredient.Nutrients) != 2 ||
+		ingredient.Nutrients[0].Name != "Energy" || ingredient.Nutrients[0].Unit != catalog.UnitKcal ||
+		ingredient.Nutrients[1].Name != "Protein" || ingredient.Nutrients[1].AmountPer100g != 13.15 {
+		t.Errorf("GetIngredient nutrients = %+v", ingredient.Nutrients)
+	}
+
+	replaced, err := m.ReplaceIngredient(ctx, &Ingredient{
+		IngredientID: ingredientID,
+		Name:         "Rolled oats",
+		Nutrients:    []NutrientValue{{Name: "Fat", AmountPer100g: 6.5}},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceIngredient: %v", err)
+	}
+	if replaced.Name != "Rolled oats" || len(replaced.Nutrients) != 1 || replaced.Nutrients[0].Name != "Fat" {
+		t.Errorf("ReplaceIngredient = %+v", replaced)
+	}
+
+	patched, err := m.SetIngredientNutrients(ctx, ingredientID, []NutrientValue{{Name: "Fat", AmountPer100g: 7}, {Name: "Fiber", AmountPer100g: 10}})
+	if err != nil {
+		t.Fatalf("SetIngredientNutrients: %v", err)
+	}
+	if len(patched.Nutrients) != 2 || patched.Nutrients[0].AmountPer100g != 7 {
+		t.Errorf("SetIngredientNutrients = %+v", patched.Nutrients)
+	}
+
+	mealID, err := m.CreateMeal(ctx, &Meal{UserID: alice, Name: "Porridge", Ingredients: []MealIngredient{{IngredientID: ingredientID, AmountInGrams: 80}}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	if _, err := m.DeleteIngredient(ctx, ingredientID); err != nil {
+		t.Fatalf("DeleteIngredient: %v", err)
+	}
+	if _, err := m.GetIngredient(ctx, ingredientID); !errors.Is(err, ErrIngredientNotFound) {
+		t.Errorf("GetIngredient after DeleteIngredient: got %v, want ErrIngredientNotFound", err)
+	}
+	// ON DELETE CASCADE removes the meal line
+	meal, err := m.GetMeal(ctx, alice, mealID)
+	if err != nil {
+		t.Fatalf("GetMeal: %v", err)
+	}
+	if len(meal.Ingredients) != 0 {
+		t.Errorf("meal ingredients after DeleteIngredient = %+v, want none", meal.Ingredients)
+	}
+	if _, err := m.DeleteIngredient(ctx, ingredientID); !errors.Is(err, ErrIngredientNotFound) {
+		t.Errorf("DeleteIngredient twice: got %v, want ErrIngredientNotFound", err)
+	}
+}
+
+func TestMemoryBarcodesAreUnique(t *testing.T) {
+	ctx := context.Background()
+	m, _, _ := newTestMemory(t)
+
+	const code = "04006381333931"
+	ingredientID, err := m.CreateIngredient(ctx, &Ingredient{Name: "Pen", Barcode: code, Brand: "Stabilo"})
+	if err != nil {
+		t.Fatalf("CreateIngredient: %v", err)
+	}
+	if _, err := m.CreateIngredient(ctx, &Ingredient{Name: "Other pen", Barcode: code}); !errors.Is(err, ErrBarcodeExists) {
+		t.Errorf("CreateIngredient with a taken barcode: got %v, want ErrBarcodeExists", err)
+	}
+
+	found, err := m.GetIngredientByBarcode(ctx, code)
+	if err != nil {
+		t.Fatalf("GetIngredientByBarcode: %v", err)
+	}
+	if found.IngredientID != ingredientID || found.Brand != "Stabilo" {
+		t.Errorf("GetIngredientByBarcode = %+v", found)
+	}
+	if _, err := m.GetIngredientByBarcode(ctx, "00000000000000"); !errors.Is(err, ErrIngredientNotFound) {
+		t.Errorf("GetIngredientByBarcode of an unknown code: got %v, want ErrIngredientNotFound", err)
+	}
+}
+
+func TestMemoryCreateUserRejectsTakenNames(t *testing.T) {
+	ctx := context.Background()
+	m, _, _ := newTestMemory(t)
+
+	for _, user := range []User{
+		{Username: "alice", Email: "other@example.com"},
+		{Username: "carol", Email: "alice@example.com"},
+	} {
+		if _, err := m.CreateUser(ctx, &user); !errors.Is(err, ErrUserExists) {
+			t.Errorf("CreateUser(%q, %q): got %v, want ErrUserExists", user.Username, user.Email, err)
+		}
+	}
+}
+
+// amounts maps nutrient names to amounts per 100 g.
+func amounts(values []NutrientValue) map[string]float64 {
+	byName := make(map[string]float64)
+	for _, value := range values {
+		byName[value.Name] = value.AmountPer100g
+	}
+	return byName
+}
+
+// expectAmounts compares amounts by name, allowing for float rounding.
+func expectAmounts(t *testing.T, what string, got map[string]float64, want map[string]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+		return
+	}
+	for name, amount := range want {
+		if diff := got[name] - amount; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s = %v, want %v", what, got, want)
+			return
+		}
+	}
+}
+
+func TestMemoryRecipeLifecycle(t *testing.T) {
+	ctx := context.Background()
+	m, alice, _ := newTestMemory(t)
+	rice := createTestIngredient(t, m, "Rice", NutrientValue{Name: "Carbohydrate", AmountPer100g: 80}, NutrientValue{Name: "Protein", AmountPer100g: 7})
+	oil := createTestIngredient(t, m, "Oil", NutrientValue{Name: "Fat", AmountPer100g: 100})
+
+	// 100 g of rice and 10 g of oil absorb water and cook to 250 g
+	recipeID, err := m.CreateRecipe(ctx, &Recipe{
+		Name:         "Fried rice",
+		YieldInGrams: 250,
+		Ingredients:  []RecipeIngredient{{IngredientID: rice, AmountInGrams: 100}, {IngredientID: oil, AmountInGrams: 10}},
+	})
+	if err != nil {
+		t.Fatalf("CreateRecipe: %v", err)
+	}
+	recipe, err := m.GetRecipe(ctx, recipeID)
+	if err != nil {
+		t.Fatalf("GetRecipe: %v", err)
+	}
+	if recipe.Name != "Fried rice" || len(recipe.Ingredients) != 2 || recipe.Ingredients[0].Name != "Oil" {
+		t.Errorf("GetRecipe = %+v", recipe)
+	}
+	expectAmounts(t, "recipe nutrients", amounts(recipe.Nutrients), map[string]float64{"Carbohydrate": 32, "Protein": 2.8, "Fat": 4})
+
+	// the recipe is an ingredient that meals can use
+	eatenAt := time.Date(2023, 11, 20, 12, 0, 0, 0, time.UTC)
+	mealID, err := m.CreateMeal(ctx, &Meal{UserID: alice, Name: "Lunch", Date: eatenAt, Time: eatenAt, Ingredients: []MealIngredient{{IngredientID: recipeID, AmountInGrams: 50}}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	nutrition, err := m.GetMealNutrition(ctx, alice, mealID)
+	if err != nil {
+		t.Fatalf("GetMealNutrition: %v", err)
+	}
+	totals := make(map[string]float64)
+	for _, total := range nutrition.Totals {
+		totals[total.Name] = total.Amount
+	}
+	expectAmounts(t, "meal totals", totals, map[string]float64{"Carbohydrate": 16, "Protein": 1.4, "Fat": 2})
+
+	// changing an ingredient derives the recipe again
+	if _, err := m.SetIngredientNutrients(ctx, rice, []NutrientValue{{Name: "Protein", AmountPer100g: 10}}); err != nil {
+		t.Fatalf("SetIngredientNutrients: %v", err)
+	}
+	if recipe, err = m.GetRecipe(ctx, recipeID); err != nil {
+		t.Fatalf("GetRecipe: %v", err)
+	}
+	expectAmounts(t, "recipe nutrients after changing an ingredient", amounts(recipe.Nutrients), map[string]float64{"Carbohydrate": 32, "Protein": 4, "Fat": 4})
+
+	recipe, err = m.ReplaceRecipe(ctx, &Recipe{RecipeID: recipeID, Name: "Plain rice", YieldInGrams: 200, Ingredients: []RecipeIngredient{{IngredientID: rice, AmountInGrams: 100}}})
+	if err != nil {
+		t.Fatalf("ReplaceRecipe: %v", err)
+	}
+	expectAmounts(t, "replaced recipe nutrients", amounts(recipe.Nutrients), map[string]float64{"Carbohydrate": 40, "Protein": 5})
+
+	// the derived nutrients cannot be written directly
+	if _, err := m.ReplaceIngredient(ctx, &Ingredient{IngredientID: recipeID, Name: "Plain rice"}); !errors.Is(err, ErrIngredientIsRecipe) {
+		t.Errorf("ReplaceIngredient of a recipe: got %v, want ErrIngredientIsRecipe", err)
+	}
+	if _, err := m.SetIngredientNutrients(ctx, recipeID, []NutrientValue{{Name: "Fat", AmountPer100g: 1}}); !errors.Is(err, ErrIngredientIsRecipe) {
+		t.Errorf("SetIngredientNutrients of a recipe: got %v, want ErrIngredientIsRecipe", err)
+	}
+	if _, err := m.DeleteIngredient(ctx, rice); !errors.Is(err, ErrIngredientInUse) {
+		t.Errorf("DeleteIngredient of a recipe ingredient: got %v, want ErrIngredientInUse", err)
+	}
+
+	if _, err := m.DeleteRecipe(ctx, recipeID); err != nil {
+		t.Fatalf("DeleteRecipe: %v", err)
+	}
+	if _, err := m.GetRecipe(ctx, recipeID); !errors.Is(err, ErrRecipeNotFound) {
+		t.Errorf("GetRecipe after DeleteRecipe: got %v, want ErrRecipeNotFound", err)
+	}
+	meal, err := m.GetMeal(ctx, alice, mealID)
+	if err != nil {
+		t.Fatalf("GetMeal: %v", err)
+	}
+	if len(meal.Ingredients) != 0 {
+		t.Errorf("meal ingredients after DeleteRecipe = %+v, want none", meal.Ingredients)
+	}
+}
+
+func TestMemoryRecipeRejectsBadLines(t *testing.T) {
+	ctx := context.Background()
+	m, _, _ := newTestMemory(t)
+	rice := createTestIngredient(t, m, "Rice")
+	recipeID, err := m.CreateRecipe(ctx, &Recipe{Name: "Boiled rice", YieldInGrams: 250, Ingredients: []RecipeIngredient{{IngredientID: rice, AmountInGrams: 100}}})
+	if err != nil {
+		t.Fatalf("CreateRecipe: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		recipe Recipe
+		want   error
+	}{
+		{"taken name", Recipe{Name: "Rice", YieldInGrams: 100, Ingredients: []RecipeIngredient{{IngredientID: rice, AmountInGrams: 100}}}, ErrIngredientExists},
+		{"unknown ingredient", Recipe{Name: "Mystery", YieldInGrams: 100, Ingredients: []RecipeIngredient{{IngredientID: 999, AmountInGrams: 100}}}, ErrIngredientNotFound},
+		{"nested recipe", Recipe{Name: "Rice bowl", YieldInGrams: 100, Ingredients: []RecipeIngredient{{IngredientID: recipeID, AmountInGrams: 100}}}, ErrNestedRecipe},
+		{"duplicate line", Recipe{Name: "Double rice", YieldInGrams: 100, Ingredients: []RecipeIngredient{{IngredientID: rice, AmountInGrams: 100}, {IngredientID: rice, AmountInGrams: 50}}}, ErrRecipeIngredientExists},
+	}
+	for _, test := range tests {
+		if _, err := m.CreateRecipe(ctx, &test.recipe); !errors.Is(err, test.want) {
+			t.Errorf("CreateRecipe with a %s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+	if _, err := m.ReplaceRecipe(ctx, &Recipe{RecipeID: 999, Name: "Nothing", YieldInGrams: 1}); !errors.Is(err, ErrRecipeNotFound) {
+		t.Errorf("ReplaceRecipe of an unknown recipe: got %v, want ErrRecipeNotFound", err)
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+// Postgres implements Store on top of the schema created by the migrations
+// package.
+type Postgres struct {
+	db *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx so helpers can run either
+// inside or outside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// withTx runs fn inside a transaction, committing on success and rolling back
+// when fn returns an error.
+func (p *Postgres) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign_key_violation.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// ViolationKind is the kind of constraint a database write violated.
+type ViolationKind int
+
+const (
+	UniqueViolation ViolationKind = iota
+	PrimaryKeyViolation
+	// ForeignKeyViolation is a write naming a row that does not exist.
+	ForeignKeyViolation
+	// ReferencedViolation is a delete or key change of a row that other rows
+	// still reference.
+	ReferencedViolation
+	CheckViolation
+	NotNullViolation
+)
+
+// Violation describes a violated database constraint.
+type Violation struct {
+	Kind       ViolationKind
+	Constraint string
+}
+
+// ConstraintViolation reports the constraint behind err when err comes from a
+// database write the store has no more specific error for, such as a race the
+// store's own checks could not see.
+func ConstraintViolation(err error) (Violation, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return Violation{}, false
+	}
+	violation := Violation{Constraint: pqErr.Constraint}
+	switch pqErr.Code {
+	case "23505":
+		violation.Kind = UniqueViolation
+		if strings.HasSuffix(pqErr.Constraint, "_pkey") {
+			violation.Kind = PrimaryKeyViolation
+		}
+	case "23503":
+		violation.Kind = ForeignKeyViolation
+		if strings.Contains(pqErr.Detail, "is still referenced") {
+			violation.Kind = ReferencedViolation
+		}
+	case "23514":
+		violation.Kind = CheckViolation
+	case "23502":
+		violation.Kind = NotNullViolation
+	default:
+		return Violation{}, false
+	}
+	return violation, true
+}
+
+// OutOfRange reports whether err is a Postgres numeric_value_out_of_range,
+// raised for values that do not fit their column. Handlers validate what
+// clients send, so this is left for values the database derives itself, such
+// as the nutrients of a recipe with a tiny yield.
+func OutOfRange(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "22003"
+}
+
+// Unavailable reports whether err means the database could not be reached or
+// dropped the connection, as opposed to rejecting the query. Such errors are
+// transient: database/sql replaces broken connections, so the same call is
+// expected to succeed once the database is back. A canceled or timed out
+// context is not an outage, whatever error the driver wrapped it in.
+func Unavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	// dialing the database or reading from and writing to its connection
+	// failed; lib/pq itself turns a connection closed by the server into
+	// driver.ErrBadConn
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03", "53300":
+			// admin_shutdown, crash_shutdown, cannot_connect_now, too_many_connections
+			return true
+		}
+		// class 08 is connection_exception
+		return pqErr.Code.Class() == "08"
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (p *Postgres) ListGoals(ctx context.Context, userID int64) ([]Goal, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT Nutrients.NutrientID, Nutrients.Name, COALESCE(Nutrients.Unit, ''), Nutrition_Goals.MinAmount, Nutrition_Goals.MaxAmount, Nutrition_Goals.UpdatedAt FROM Nutrition_Goals INNER JOIN Nutrients ON Nutrients.NutrientID = Nutrition_Goals.NutrientID WHERE Nutrition_Goals.UserID = $1 ORDER BY Nutrients.Name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying Nutrition_Goals table: %w", err)
+	}
+	defer rows.Close()
+
+	var goals []Goal
+	for rows.Next() {
+		var goal Goal
+		var minAmount, maxAmount sql.NullFloat64
+		if err := rows.Scan(&goal.NutrientID, &goal.Name, &goal.Unit, &minAmount, &maxAmount, &goal.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning goal: %w", err)
+		}
+		setGoalBounds(&goal, minAmount, maxAmount)
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+func (p *Postgres) SetGoal(ctx context.Context, userID int64, goal Goal) (*Goal, bool, error) {
+	nutrient, err := getNutrientByName(ctx, p.db, goal.Name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stored := Goal{NutrientID: nutrient.NutrientID, Name: nutrient.Name, Unit: nutrient.Unit}
+	var minAmount, maxAmount sql.NullFloat64
+	var created bool
+	// the bounds are returned as stored, rounded to the column's scale; xmax
+	// is only zero for a freshly inserted row
+	err = p.db.QueryRowContext(ctx, "INSERT INTO Nutrition_Goals (UserID, NutrientID, MinAmount, MaxAmount) VALUES ($1, $2, $3, $4) ON CONFLICT (UserID, NutrientID) DO UPDATE SET MinAmount = EXCLUDED.MinAmount, MaxAmount = EXCLUDED.MaxAmount, UpdatedAt = CURRENT_TIMESTAMP RETURNING MinAmount, MaxAmount, UpdatedAt, (xmax = 0)", userID, nutrient.NutrientID, goal.Min, goal.Max).Scan(&minAmount, &maxAmount, &stored.UpdatedAt, &created)
+	if err != nil {
+		return nil, false, fmt.Errorf("upserting into Nutrition_Goals table: %w", err)
+	}
+	setGoalBounds(&stored, minAmount, maxAmount)
+	return &stored, created, nil
+}
+
+// setGoalBounds sets the bounds of goal from their nullable columns.
+func setGoalBounds(goal *Goal, minAmount, maxAmount sql.NullFloat64) {
+	if minAmount.Valid {
+		goal.Min = &minAmount.Float64
+	}
+	if maxAmount.Valid {
+		goal.Max = &maxAmount.Float64
+	}
+}
+
+func (p *Postgres) DeleteGoal(ctx context.Context, userID int64, nutrientName string) error {
+	result, err := p.db.ExecContext(ctx, "DELETE FROM Nutrition_Goals USING Nutrients WHERE Nutrients.NutrientID = Nutrition_Goals.NutrientID AND Nutrition_Goals.UserID = $1 AND Nutrients.Name = $2", userID, nutrientName)
+	if err != nil {
+		return fmt.Errorf("deleting from Nutrition_Goals table: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
@@ -0,0 +1,439 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+func (p *Postgres) CreateIngredient(ctx context.Context, ingredient *Ingredient) (int64, error) {
+	var ingredientID int64
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkIngredientName(ctx, tx, ingredient.Name, 0); err != nil {
+			return err
+		}
+
+		err := tx.QueryRowContext(ctx, "INSERT INTO Ingredients (Name, Barcode, Brand) VALUES ($1, NULLIF($2, ''), NULLIF($3, '')) RETURNING IngredientID", ingredient.Name, ingredient.Barcode, ingredient.Brand).Scan(&ingredientID)
+		if isUniqueViolation(err) {
+			return ErrBarcodeExists
+		}
+		if err != nil {
+			return fmt.Errorf("inserting into Ingredients table: %w", err)
+		}
+
+		return upsertNutrientValues(ctx, tx, ingredientID, ingredient.Nutrients)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ingredientID, nil
+}
+
+func (p *Postgres) GetIngredient(ctx context.Context, ingredientID int64) (*Ingredient, error) {
+	return getIngredient(ctx, p.db, ingredientID)
+}
+
+func (p *Postgres) GetIngredientByBarcode(ctx context.Context, barcode string) (*Ingredient, error) {
+	var ingredientID int64
+	err := p.db.QueryRowContext(ctx, "SELECT IngredientID FROM Ingredients WHERE Barcode = $1", barcode).Scan(&ingredientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIngredientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying Ingredients table: %w", err)
+	}
+	return getIngredient(ctx, p.db, ingredientID)
+}
+
+func getIngredient(ctx context.Context, q querier, ingredientID int64) (*Ingredient, error) {
+	var ingredient Ingredient
+	err := q.QueryRowContext(ctx, "SELECT IngredientID, Name, COALESCE(Barcode, ''), COALESCE(Brand, '') FROM Ingredients WHERE IngredientID = $1", ingredientID).Scan(&ingredient.IngredientID, &ingredient.Name, &ingredient.Barcode, &ingredient.Brand)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIngredientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying Ingredients table: %w", err)
+	}
+
+	nutrients, err := listNutrientValues(ctx, q, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+	ingredient.Nutrients = nutrients
+	return &ingredient, nil
+}
+
+func (p *Postgres) ReplaceIngredient(ctx context.Context, ingredient *Ingredient) (*Ingredient, error) {
+	var replaced *Ingredient
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockIngredient(ctx, tx, ingredient.IngredientID); err != nil {
+			return err
+		}
+		if err := checkNotRecipe(ctx, tx, ingredient.IngredientID); err != nil {
+			return err
+		}
+		if err := checkIngredientName(ctx, tx, ingredient.Name, ingredient.IngredientID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "UPDATE Ingredients SET Name = $2, Barcode = NULLIF($3, ''), Brand = NULLIF($4, ''), UpdatedAt = CURRENT_TIMESTAMP WHERE IngredientID = $1", ingredient.IngredientID, ingredient.Name, ingredient.Barcode, ingredient.Brand)
+		if isUniqueViolation(err) {
+			return ErrBarcodeExists
+		}
+		if err != nil {
+			return fmt.Errorf("updating Ingredients table: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM Nutrient_Values WHERE IngredientID = $1", ingredient.IngredientID)
+		if err != nil {
+			return fmt.Errorf("deleting from Nutrient_Values table: %w", err)
+		}
+		if err := upsertNutrientValues(ctx, tx, ingredient.IngredientID, ingredient.Nutrients); err != nil {
+			return err
+		}
+		if err := deriveRecipesUsing(ctx, tx, ingredient.IngredientID); err != nil {
+			return err
+		}
+
+		replaced, err = getIngredient(ctx, tx, ingredient.IngredientID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+func (p *Postgres) SetIngredientNutrients(ctx context.Context, ingredientID int64, nutrients []NutrientValue) (*Ingredient, error) {
+	var updated *Ingredient
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockIngredient(ctx, tx, ingredientID); err != nil {
+			return err
+		}
+		if err := checkNotRecipe(ctx, tx, ingredientID); err != nil {
+			return err
+		}
+		if err := upsertNutrientValues(ctx, tx, ingredientID, nutrients); err != nil {
+			return err
+		}
+		if err := deriveRecipesUsing(ctx, tx, ingredientID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE Ingredients SET UpdatedAt = CURRENT_TIMESTAMP WHERE IngredientID = $1", ingredientID)
+		if err != nil {
+			return fmt.Errorf("updating Ingredients table: %w", err)
+		}
+
+		updated, err = getIngredient(ctx, tx, ingredientID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (p *Postgres) UpsertIngredients(ctx context.Context, ingredients []Ingredient) (*UpsertSummary, error) {
+	var summary UpsertSummary
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		nutrientIDs, err := catalogNutrientIDs(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, ingredient := range ingredients {
+			var ingredientID int64
+			var isRecipe bool
+			var err error
+			if ingredient.Barcode != "" {
+				err = tx.QueryRowContext(ctx, "SELECT IngredientID, EXISTS (SELECT 1 FROM Recipes WHERE Recipes.IngredientID = Ingredients.IngredientID) FROM Ingredients WHERE Barcode = $1 FOR UPDATE", ingredient.Barcode).Scan(&ingredientID, &isRecipe)
+			} else {
+				err = tx.QueryRowContext(ctx, "SELECT IngredientID, EXISTS (SELECT 1 FROM Recipes WHERE Recipes.IngredientID = Ingredients.IngredientID) FROM Ingredients WHERE Name = $1 ORDER BY IngredientID LIMIT 1 FOR UPDATE", ingredient.Name).Scan(&ingredientID, &isRecipe)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				if ingredient.Barcode != "" {
+					// a new product must not take the name of another ingredient
+					if err := checkIngredientName(ctx, tx, ingredient.Name, 0); errors.Is(err, ErrIngredientExists) {
+						summary.Skipped++
+						continue
+					} else if err != nil {
+						return err
+					}
+				}
+				err = tx.QueryRowContext(ctx, "INSERT INTO Ingredients (Name, Barcode, Brand) VALUES ($1, NULLIF($2, ''), NULLIF($3, '')) RETURNING IngredientID", ingredient.Name, ingredient.Barcode, ingredient.Brand).Scan(&ingredientID)
+				if err != nil {
+					return fmt.Errorf("inserting into Ingredients table: %w", err)
+				}
+				if _, err := setNutrientValues(ctx, tx, nutrientIDs, ingredientID, ingredient.Nutrients); err != nil {
+					return err
+				}
+				summary.Created++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("querying Ingredients table: %w", err)
+			}
+			if isRecipe {
+				summary.Skipped++
+				continue
+			}
+
+			changed, err := setNutrientValues(ctx, tx, nutrientIDs, ingredientID, ingredient.Nutrients)
+			if err != nil {
+				return err
+			}
+			if ingredient.Brand != "" {
+				result, err := tx.ExecContext(ctx, "UPDATE Ingredients SET Brand = $2 WHERE IngredientID = $1 AND Brand IS DISTINCT FROM $2", ingredientID, ingredient.Brand)
+				if err != nil {
+					return fmt.Errorf("updating Ingredients table: %w", err)
+				}
+				affected, err := result.RowsAffected()
+				if err != nil {
+					return err
+				}
+				changed = changed || affected > 0
+			}
+			if !changed {
+				summary.Unchanged++
+				continue
+			}
+			_, err = tx.ExecContext(ctx, "UPDATE Ingredients SET UpdatedAt = CURRENT_TIMESTAMP WHERE IngredientID = $1", ingredientID)
+			if err != nil {
+				return fmt.Errorf("updating Ingredients table: %w", err)
+			}
+			if err := deriveRecipesUsing(ctx, tx, ingredientID); err != nil {
+				return err
+			}
+			summary.Updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// setNutrientValues is upsertNutrientValues for batches: nutrient IDs come
+// from catalogNutrientIDs and it reports whether any stored amount changed.
+func setNutrientValues(ctx context.Context, tx *sql.Tx, nutrientIDs map[string]int64, ingredientID int64, nutrients []NutrientValue) (bool, error) {
+	var changed bool
+	for _, nutrient := range nutrients {
+		nutrientID, ok := nutrientIDs[nutrient.Name]
+		if !ok {
+			return false, ErrNutrientNotFound
+		}
+		result, err := tx.ExecContext(ctx, "INSERT INTO Nutrient_Values (IngredientID, NutrientID, AmountPer100g) VALUES ($1, $2, $3) ON CONFLICT (IngredientID, NutrientID) DO UPDATE SET AmountPer100g = EXCLUDED.AmountPer100g WHERE Nutrient_Values.AmountPer100g IS DISTINCT FROM EXCLUDED.AmountPer100g", ingredientID, nutrientID, nutrient.AmountPer100g)
+		if err != nil {
+			return false, fmt.Errorf("upserting into Nutrient_Values table: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		changed = changed || affected > 0
+	}
+	return changed, nil
+}
+
+func (p *Postgres) DeleteIngredient(ctx context.Context, ingredientID int64) (*Ingredient, error) {
+	var ingredient Ingredient
+	err := p.db.QueryRowContext(ctx, "DELETE FROM Ingredients WHERE IngredientID = $1 RETURNING IngredientID, Name, COALESCE(Barcode, ''), COALESCE(Brand, '')", ingredientID).Scan(&ingredient.IngredientID, &ingredient.Name, &ingredient.Barcode, &ingredient.Brand)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIngredientNotFound
+	}
+	if isForeignKeyViolation(err) {
+		return nil, ErrIngredientInUse
+	}
+	if err != nil {
+		return nil, fmt.Errorf("deleting from Ingredients table: %w", err)
+	}
+	return &ingredient, nil
+}
+
+func listNutrientValues(ctx context.Context, q querier, ingredientID int64) ([]NutrientValue, error) {
+	rows, err := q.QueryContext(ctx, "SELECT Nutrients.NutrientID, Nutrients.Name, COALESCE(Nutrients.Unit, ''), Nutrient_Values.AmountPer100g FROM Nutrients INNER JOIN Nutrient_Values ON Nutrients.NutrientID = Nutrient_Values.NutrientID WHERE Nutrient_Values.IngredientID = $1 ORDER BY Nutrients.Name", ingredientID)
+	if err != nil {
+		return nil, fmt.Errorf("querying Nutrients and Nutrient_Values tables: %w", err)
+	}
+	defer rows.Close()
+
+	var nutrients []NutrientValue
+	for rows.Next() {
+		var nutrient NutrientValue
+		if err := rows.Scan(&nutrient.NutrientID, &nutrient.Name, &nutrient.Unit, &nutrient.AmountPer100g); err != nil {
+			return nil, fmt.Errorf("scanning nutrient value: %w", err)
+		}
+		nutrients = append(nutrients, nutrient)
+	}
+	return nutrients, rows.Err()
+}
+
+// checkIngredientName returns ErrIngredientExists if another ingredient than
+// exceptID already uses name.
+func checkIngredientName(ctx context.Context, tx *sql.Tx, name string, exceptID int64) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM Ingredients WHERE Name = $1 AND IngredientID <> $2)", name, exceptID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("querying Ingredients table: %w", err)
+	}
+	if exists {
+		return ErrIngredientExists
+	}
+	return nil
+}
+
+// lockIngredient locks the ingredient row for the rest of the transaction.
+func lockIngredient(ctx context.Context, tx *sql.Tx, ingredientID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT IngredientID FROM Ingredients WHERE IngredientID = $1 FOR UPDATE", ingredientID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrIngredientNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("locking ingredient: %w", err)
+	}
+	return nil
+}
+
+// upsertNutrientValues stores the per 100 g amounts of an ingredient. Every
+// nutrient must be in the catalog.
+func upsertNutrientValues(ctx context.Context, tx *sql.Tx, ingredientID int64, nutrients []NutrientValue) error {
+	for _, nutrient := range nutrients {
+		catalogNutrient, err := getNutrientByName(ctx, tx, nutrient.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO Nutrient_Values (IngredientID, NutrientID, AmountPer100g) VALUES ($1, $2, $3) ON CONFLICT (IngredientID, NutrientID) DO UPDATE SET AmountPer100g = EXCLUDED.AmountPer100g", ingredientID, catalogNutrient.NutrientID, nutrient.AmountPer100g)
+		if err != nil {
+			return fmt.Errorf("inserting into Nutrient_Values table: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *Postgres) ListIngredients(ctx context.Context, filter IngredientFilter) (*IngredientPage, error) {
+	sort := filter.Sort
+	if sort == "" {
+		sort = IngredientSortName
+	}
+	var op, dir string
+	var keys int
+	switch sort {
+	case IngredientSortName, IngredientSortID:
+		op, dir = ">", "ASC"
+	case IngredientSortNameDesc, IngredientSortIDDesc:
+		op, dir = "<", "DESC"
+	default:
+		return nil, ErrInvalidSort
+	}
+	byName := sort == IngredientSortName || sort == IngredientSortNameDesc
+	if byName {
+		keys = 1
+	}
+	after, err := decodeCursor(filter.Cursor, sort, keys)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageSize(filter.Limit)
+
+	conditions := []string{"TRUE"}
+	var args []any
+	if filter.NamePrefix != "" {
+		args = append(args, escapeLike(filter.NamePrefix)+"%")
+		conditions = append(conditions, fmt.Sprintf("Name ILIKE $%d", len(args)))
+	}
+	if filter.HasNutrient != "" {
+		args = append(args, filter.HasNutrient)
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM Nutrient_Values INNER JOIN Nutrients ON Nutrients.NutrientID = Nutrient_Values.NutrientID WHERE Nutrient_Values.IngredientID = Ingredients.IngredientID AND lower(Nutrients.Name) = lower($%d))", len(args)))
+	}
+	orderBy := fmt.Sprintf("IngredientID %s", dir)
+	if byName {
+		orderBy = fmt.Sprintf("Name %s, IngredientID %s", dir, dir)
+	}
+	if after != nil {
+		if byName {
+			args = append(args, after.Keys[0], after.ID)
+			conditions = append(conditions, fmt.Sprintf("(Name, IngredientID) %s ($%d, $%d)", op, len(args)-1, len(args)))
+		} else {
+			args = append(args, after.ID)
+			conditions = append(conditions, fmt.Sprintf("IngredientID %s $%d", op, len(args)))
+		}
+	}
+	args = append(args, limit+1)
+	query := fmt.Sprintf("SELECT IngredientID, Name, COALESCE(Barcode, ''), COALESCE(Brand, '') FROM Ingredients WHERE %s ORDER BY %s LIMIT $%d", strings.Join(conditions, " AND "), orderBy, len(args))
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying Ingredients table: %w", err)
+	}
+	defer rows.Close()
+
+	var page IngredientPage
+	for rows.Next() {
+		var ingredient Ingredient
+		if err := rows.Scan(&ingredient.IngredientID, &ingredient.Name, &ingredient.Barcode, &ingredient.Brand); err != nil {
+			return nil, fmt.Errorf("scanning ingredient: %w", err)
+		}
+		page.Ingredients = append(page.Ingredients, ingredient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading ingredients: %w", err)
+	}
+	rows.Close()
+
+	if len(page.Ingredients) > limit {
+		page.Ingredients = page.Ingredients[:limit]
+		last := page.Ingredients[limit-1]
+		next := cursor{Sort: sort, ID: last.IngredientID}
+		if byName {
+			next.Keys = []string{last.Name}
+		}
+		page.NextCursor = next.encode()
+	}
+
+	if err := loadNutrientValues(ctx, p.db, page.Ingredients); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// loadNutrientValues fills in the nutrient values of several ingredients with
+// a single query.
+func loadNutrientValues(ctx context.Context, q querier, ingredients []Ingredient) error {
+	if len(ingredients) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(ingredients))
+	index := make(map[int64]int, len(ingredients))
+	for i, ingredient := range ingredients {
+		ids[i] = ingredient.IngredientID
+		index[ingredient.IngredientID] = i
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT Nutrient_Values.IngredientID, Nutrients.NutrientID, Nutrients.Name, COALESCE(Nutrients.Unit, ''), Nutrient_Values.AmountPer100g FROM Nutrients INNER JOIN Nutrient_Values ON Nutrients.NutrientID = Nutrient_Values.NutrientID WHERE Nutrient_Values.IngredientID = ANY($1) ORDER BY Nutrients.Name", pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("querying Nutrients and Nutrient_Values tables: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ingredientID int64
+		var nutrient NutrientValue
+		if err := rows.Scan(&ingredientID, &nutrient.NutrientID, &nutrient.Name, &nutrient.Unit, &nutrient.AmountPer100g); err != nil {
+			return fmt.Errorf("scanning nutrient value: %w", err)
+		}
+		ingredient := &ingredients[index[ingredientID]]
+		ingredient.Nutrients = append(ingredient.Nutrients, nutrient)
+	}
+	return rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
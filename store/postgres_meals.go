@@ -0,0 +1,333 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func (p *Postgres) CreateMeal(ctx context.Context, meal *Meal) (int64, error) {
+	var mealID int64
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "INSERT INTO Meals (UserID, Name, Date, Time) VALUES ($1, $2, $3, $4) RETURNING MealID", meal.UserID, meal.Name, meal.Date, meal.Time).Scan(&mealID)
+		if err != nil {
+			return fmt.Errorf("inserting into Meals table: %w", err)
+		}
+
+		for _, ingredient := range meal.Ingredients {
+			if err := insertMealIngredient(ctx, tx, mealID, ingredient); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return mealID, nil
+}
+
+func (p *Postgres) GetMeal(ctx context.Context, userID int64, mealID int64) (*Meal, error) {
+	return getMeal(ctx, p.db, userID, mealID)
+}
+
+func (p *Postgres) AddIngredientToMeal(ctx context.Context, userID int64, mealID int64, ingredient MealIngredient) error {
+	return p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockMeal(ctx, tx, userID, mealID, time.Time{}); err != nil {
+			return err
+		}
+		if err := insertMealIngredient(ctx, tx, mealID, ingredient); err != nil {
+			return err
+		}
+		return touchMeal(ctx, tx, mealID)
+	})
+}
+
+func (p *Postgres) SetMealIngredient(ctx context.Context, userID int64, mealID int64, ingredient MealIngredient) (bool, error) {
+	var created bool
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockMeal(ctx, tx, userID, mealID, time.Time{}); err != nil {
+			return err
+		}
+		// xmax is only zero for a freshly inserted row
+		err := tx.QueryRowContext(ctx, "INSERT INTO Meal_Ingredients (MealID, IngredientID, QuantityInGrams) VALUES ($1, $2, $3) ON CONFLICT (MealID, IngredientID) DO UPDATE SET QuantityInGrams = EXCLUDED.QuantityInGrams RETURNING (xmax = 0)", mealID, ingredient.IngredientID, ingredient.AmountInGrams).Scan(&created)
+		if isForeignKeyViolation(err) {
+			return ErrIngredientNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("upserting into Meal_Ingredients table: %w", err)
+		}
+		return touchMeal(ctx, tx, mealID)
+	})
+	if err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+func (p *Postgres) UpdateMeal(ctx context.Context, userID int64, mealID int64, update MealUpdate, ifUpdatedAt time.Time) (*Meal, error) {
+	var meal *Meal
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockMeal(ctx, tx, userID, mealID, ifUpdatedAt); err != nil {
+			return err
+		}
+
+		var name sql.NullString
+		if update.Name != nil {
+			name = sql.NullString{String: *update.Name, Valid: true}
+		}
+		var dateTime sql.NullTime
+		if update.DateTime != nil {
+			dateTime = sql.NullTime{Time: *update.DateTime, Valid: true}
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE Meals SET Name = COALESCE($2, Name), Date = COALESCE($3, Date), Time = COALESCE($4, Time), UpdatedAt = CURRENT_TIMESTAMP WHERE MealID = $1", mealID, name, dateTime, dateTime)
+		if err != nil {
+			return fmt.Errorf("updating Meals table: %w", err)
+		}
+
+		meal, err = getMeal(ctx, tx, userID, mealID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meal, nil
+}
+
+func (p *Postgres) RemoveIngredientFromMeal(ctx context.Context, userID int64, mealID int64, ingredientID int64, ifUpdatedAt time.Time) (*Meal, error) {
+	var meal *Meal
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockMeal(ctx, tx, userID, mealID, ifUpdatedAt); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM Meal_Ingredients WHERE MealID = $1 AND IngredientID = $2", mealID, ingredientID)
+		if err != nil {
+			return fmt.Errorf("deleting from Meal_Ingredients table: %w", err)
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrMealIngredientNotFound
+		}
+		if err := touchMeal(ctx, tx, mealID); err != nil {
+			return err
+		}
+
+		meal, err = getMeal(ctx, tx, userID, mealID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meal, nil
+}
+
+func (p *Postgres) DeleteMeal(ctx context.Context, userID int64, mealID int64, ifUpdatedAt time.Time) (*Meal, error) {
+	var meal *Meal
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockMeal(ctx, tx, userID, mealID, ifUpdatedAt); err != nil {
+			return err
+		}
+
+		var err error
+		meal, err = getMeal(ctx, tx, userID, mealID)
+		if err != nil {
+			return err
+		}
+
+		// Meal_Ingredients rows are removed by ON DELETE CASCADE
+		_, err = tx.ExecContext(ctx, "DELETE FROM Meals WHERE MealID = $1", mealID)
+		if err != nil {
+			return fmt.Errorf("deleting from Meals table: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meal, nil
+}
+
+// insertMealIngredient adds a line to a meal, translating key violations into
+// ErrMealIngredientExists and ErrIngredientNotFound.
+func insertMealIngredient(ctx context.Context, tx *sql.Tx, mealID int64, ingredient MealIngredient) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO Meal_Ingredients (MealID, IngredientID, QuantityInGrams) VALUES ($1, $2, $3)", mealID, ingredient.IngredientID, ingredient.AmountInGrams)
+	if isUniqueViolation(err) {
+		return ErrMealIngredientExists
+	}
+	if isForeignKeyViolation(err) {
+		return ErrIngredientNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("inserting into Meal_Ingredients table: %w", err)
+	}
+	return nil
+}
+
+func getMeal(ctx context.Context, q querier, userID int64, mealID int64) (*Meal, error) {
+	var meal Meal
+	err := q.QueryRowContext(ctx, "SELECT MealID, UserID, Name, Date, Time, UpdatedAt FROM Meals WHERE MealID = $1 AND UserID = $2", mealID, userID).Scan(&meal.MealID, &meal.UserID, &meal.Name, &meal.Date, &meal.Time, &meal.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMealNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying Meals table: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT Meal_Ingredients.IngredientID, Meal_Ingredients.QuantityInGrams, Ingredients.Name FROM Meal_Ingredients INNER JOIN Ingredients ON Ingredients.IngredientID = Meal_Ingredients.IngredientID WHERE Meal_Ingredients.MealID = $1 ORDER BY Meal_Ingredients.IngredientID", mealID)
+	if err != nil {
+		return nil, fmt.Errorf("querying Meal_Ingredients table: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ingredient MealIngredient
+		if err := rows.Scan(&ingredient.IngredientID, &ingredient.AmountInGrams, &ingredient.Name); err != nil {
+			return nil, fmt.Errorf("scanning meal ingredient: %w", err)
+		}
+		meal.Ingredients = append(meal.Ingredients, ingredient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading meal ingredients: %w", err)
+	}
+	return &meal, nil
+}
+
+// lockMeal locks the meal row for the rest of the transaction after checking
+// that it belongs to userID and, when ifUpdatedAt is non-zero, that it has not
+// been modified since.
+func lockMeal(ctx context.Context, tx *sql.Tx, userID int64, mealID int64, ifUpdatedAt time.Time) error {
+	var updatedAt time.Time
+	err := tx.QueryRowContext(ctx, "SELECT UpdatedAt FROM Meals WHERE MealID = $1 AND UserID = $2 FOR UPDATE", mealID, userID).Scan(&updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMealNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("locking meal: %w", err)
+	}
+	if !ifUpdatedAt.IsZero() && !updatedAt.Equal(ifUpdatedAt) {
+		return ErrMealModified
+	}
+	return nil
+}
+
+// touchMeal bumps Meals.UpdatedAt after one of its ingredient lines changed.
+func touchMeal(ctx context.Context, tx *sql.Tx, mealID int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE Meals SET UpdatedAt = CURRENT_TIMESTAMP WHERE MealID = $1", mealID)
+	if err != nil {
+		return fmt.Errorf("updating Meals table: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) ListMeals(ctx context.Context, userID int64, filter MealFilter) (*MealPage, error) {
+	sort := filter.Sort
+	if sort == "" {
+		sort = MealSortDateDesc
+	}
+	var op, dir string
+	switch sort {
+	case MealSortDate:
+		op, dir = ">", "ASC"
+	case MealSortDateDesc:
+		op, dir = "<", "DESC"
+	default:
+		return nil, ErrInvalidSort
+	}
+	after, err := decodeCursor(filter.Cursor, sort, 2)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageSize(filter.Limit)
+
+	conditions := []string{"UserID = $1"}
+	args := []any{userID}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.Format(cursorDateLayout))
+		conditions = append(conditions, fmt.Sprintf("Date >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.Format(cursorDateLayout))
+		conditions = append(conditions, fmt.Sprintf("Date <= $%d", len(args)))
+	}
+	if after != nil {
+		args = append(args, after.Keys[0], after.Keys[1], after.ID)
+		conditions = append(conditions, fmt.Sprintf("(Date, Time, MealID) %s ($%d, $%d, $%d)", op, len(args)-2, len(args)-1, len(args)))
+	}
+	args = append(args, limit+1)
+	query := fmt.Sprintf("SELECT MealID, UserID, Name, Date, Time, UpdatedAt FROM Meals WHERE %s ORDER BY Date %s, Time %s, MealID %s LIMIT $%d", strings.Join(conditions, " AND "), dir, dir, dir, len(args))
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying Meals table: %w", err)
+	}
+	defer rows.Close()
+
+	var page MealPage
+	for rows.Next() {
+		var meal Meal
+		if err := rows.Scan(&meal.MealID, &meal.UserID, &meal.Name, &meal.Date, &meal.Time, &meal.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning meal: %w", err)
+		}
+		page.Meals = append(page.Meals, meal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading meals: %w", err)
+	}
+	rows.Close()
+
+	if len(page.Meals) > limit {
+		page.Meals = page.Meals[:limit]
+		last := page.Meals[limit-1]
+		page.NextCursor = cursor{Sort: sort, Keys: mealSortKeys(&last), ID: last.MealID}.encode()
+	}
+
+	if err := loadMealIngredients(ctx, p.db, page.Meals); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// loadMealIngredients fills in the ingredient lines of several meals with a
+// single query.
+func loadMealIngredients(ctx context.Context, q querier, meals []Meal) error {
+	if len(meals) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(meals))
+	index := make(map[int64]int, len(meals))
+	for i, meal := range meals {
+		ids[i] = meal.MealID
+		index[meal.MealID] = i
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT Meal_Ingredients.MealID, Meal_Ingredients.IngredientID, Meal_Ingredients.QuantityInGrams, Ingredients.Name FROM Meal_Ingredients INNER JOIN Ingredients ON Ingredients.IngredientID = Meal_Ingredients.IngredientID WHERE Meal_Ingredients.MealID = ANY($1) ORDER BY Meal_Ingredients.MealID, Meal_Ingredients.IngredientID", pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("querying Meal_Ingredients table: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mealID int64
+		var ingredient MealIngredient
+		if err := rows.Scan(&mealID, &ingredient.IngredientID, &ingredient.AmountInGrams, &ingredient.Name); err != nil {
+			return fmt.Errorf("scanning meal ingredient: %w", err)
+		}
+		meal := &meals[index[mealID]]
+		meal.Ingredients = append(meal.Ingredients, ingredient)
+	}
+	return rows.Err()
+}
+
+// mealSortKeys returns the cursor keys of a meal for the date sort orders.
+func mealSortKeys(meal *Meal) []string {
+	return []string{meal.Date.Format(cursorDateLayout), meal.Time.Format(cursorTimeLayout)}
+}
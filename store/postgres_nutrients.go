@@ -0,0 +1,105 @@
+package store
+
+import (
+	"assignment2/catalog"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+func (p *Postgres) GetNutrientByName(ctx context.Context, name string) (*Nutrient, error) {
+	return getNutrientByName(ctx, p.db, name)
+}
+
+func (p *Postgres) ListNutrients(ctx context.Context) ([]Nutrient, error) {
+	return listNutrients(ctx, p.db)
+}
+
+func (p *Postgres) SyncNutrientCatalog(ctx context.Context, nutrients []catalog.Nutrient) error {
+	return p.withTx(ctx, func(tx *sql.Tx) error {
+		// keep concurrently starting instances from inserting the same rows
+		if _, err := tx.ExecContext(ctx, "LOCK TABLE Nutrients IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return fmt.Errorf("locking Nutrients table: %w", err)
+		}
+		existing, err := listNutrients(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, plan := range planCatalogSync(existing, nutrients) {
+			if plan.keep == 0 {
+				_, err := tx.ExecContext(ctx, "INSERT INTO Nutrients (Name, Unit, Category) VALUES ($1, $2, $3)", plan.nutrient.Name, plan.nutrient.Unit, plan.nutrient.Category)
+				if err != nil {
+					return fmt.Errorf("inserting into Nutrients table: %w", err)
+				}
+				continue
+			}
+
+			for _, nutrientID := range plan.merge {
+				log.Printf("Merging nutrient %d into %s\n", nutrientID, plan.nutrient.Name)
+				_, err := tx.ExecContext(ctx, "INSERT INTO Nutrient_Values (IngredientID, NutrientID, AmountPer100g) SELECT IngredientID, $1, AmountPer100g FROM Nutrient_Values WHERE NutrientID = $2 ON CONFLICT (IngredientID, NutrientID) DO NOTHING", plan.keep, nutrientID)
+				if err != nil {
+					return fmt.Errorf("merging Nutrient_Values rows: %w", err)
+				}
+				if _, err := tx.ExecContext(ctx, "DELETE FROM Nutrients WHERE NutrientID = $1", nutrientID); err != nil {
+					return fmt.Errorf("deleting from Nutrients table: %w", err)
+				}
+			}
+
+			_, err := tx.ExecContext(ctx, "UPDATE Nutrients SET Name = $2, Unit = $3, Category = $4, UpdatedAt = CURRENT_TIMESTAMP WHERE NutrientID = $1 AND (Name, Unit, Category) IS DISTINCT FROM ($2, $3, $4)", plan.keep, plan.nutrient.Name, plan.nutrient.Unit, plan.nutrient.Category)
+			if err != nil {
+				return fmt.Errorf("updating Nutrients table: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func listNutrients(ctx context.Context, q querier) ([]Nutrient, error) {
+	rows, err := q.QueryContext(ctx, "SELECT NutrientID, Name, COALESCE(Unit, ''), COALESCE(Category, '') FROM Nutrients ORDER BY NutrientID")
+	if err != nil {
+		return nil, fmt.Errorf("querying Nutrients table: %w", err)
+	}
+	defer rows.Close()
+
+	var nutrients []Nutrient
+	for rows.Next() {
+		var nutrient Nutrient
+		if err := rows.Scan(&nutrient.NutrientID, &nutrient.Name, &nutrient.Unit, &nutrient.Category); err != nil {
+			return nil, fmt.Errorf("scanning nutrient: %w", err)
+		}
+		nutrients = append(nutrients, nutrient)
+	}
+	return nutrients, rows.Err()
+}
+
+// catalogNutrientIDs maps the name of every catalog nutrient to its ID.
+func catalogNutrientIDs(ctx context.Context, q querier) (map[string]int64, error) {
+	nutrients, err := listNutrients(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	nutrientIDs := make(map[string]int64, len(nutrients))
+	for _, nutrient := range nutrients {
+		if nutrient.Unit != "" {
+			nutrientIDs[nutrient.Name] = nutrient.NutrientID
+		}
+	}
+	return nutrientIDs, nil
+}
+
+// getNutrientByName only finds catalog nutrients, rows left over from before
+// the catalog have no unit and are never written to again.
+func getNutrientByName(ctx context.Context, q querier, name string) (*Nutrient, error) {
+	var nutrient Nutrient
+	err := q.QueryRowContext(ctx, "SELECT NutrientID, Name, Unit, Category FROM Nutrients WHERE Name = $1 AND Unit IS NOT NULL", name).Scan(&nutrient.NutrientID, &nutrient.Name, &nutrient.Unit, &nutrient.Category)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNutrientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying Nutrients table: %w", err)
+	}
+	return &nutrient, nil
+}
@@ -0,0 +1,243 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// mealNutritionSQL returns one row per ingredient line and nutrient plus, via
+// the second grouping set, one row per nutrient with the meal total. The
+// LEFT JOINs keep a row for meals without ingredients so that an empty result
+// means the meal does not exist.
+const mealNutritionSQL = `
+SELECT
+    Meal_Ingredients.IngredientID,
+    Ingredients.Name,
+    Meal_Ingredients.QuantityInGrams,
+    Nutrients.NutrientID,
+    Nutrients.Name,
+    COALESCE(Nutrients.Unit, ''),
+    SUM(Nutrient_Values.AmountPer100g * Meal_Ingredients.QuantityInGrams / 100),
+    GROUPING(Meal_Ingredients.IngredientID) = 1
+FROM Meals
+LEFT JOIN Meal_Ingredients ON Meal_Ingredients.MealID = Meals.MealID
+LEFT JOIN Ingredients ON Ingredients.IngredientID = Meal_Ingredients.IngredientID
+LEFT JOIN Nutrient_Values ON Nutrient_Values.IngredientID = Meal_Ingredients.IngredientID
+LEFT JOIN Nutrients ON Nutrients.NutrientID = Nutrient_Values.NutrientID
+WHERE Meals.MealID = $1 AND Meals.UserID = $2
+GROUP BY GROUPING SETS (
+    (Meal_Ingredients.IngredientID, Ingredients.Name, Meal_Ingredients.QuantityInGrams, Nutrients.NutrientID, Nutrients.Name, Nutrients.Unit),
+    (Nutrients.NutrientID, Nutrients.Name, Nutrients.Unit)
+)
+ORDER BY 8, Meal_Ingredients.IngredientID, Nutrients.Name
+`
+
+func (p *Postgres) GetMealNutrition(ctx context.Context, userID int64, mealID int64) (*MealNutrition, error) {
+	rows, err := p.db.QueryContext(ctx, mealNutritionSQL, mealID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying meal nutrition: %w", err)
+	}
+	defer rows.Close()
+
+	var found bool
+	nutrition := &MealNutrition{MealID: mealID}
+	for rows.Next() {
+		found = true
+		var ingredientID, nutrientID sql.NullInt64
+		var ingredientName, nutrientName sql.NullString
+		var unit string
+		var quantity, amount sql.NullFloat64
+		var isTotal bool
+		if err := rows.Scan(&ingredientID, &ingredientName, &quantity, &nutrientID, &nutrientName, &unit, &amount, &isTotal); err != nil {
+			return nil, fmt.Errorf("scanning meal nutrition: %w", err)
+		}
+
+		var total *NutrientTotal
+		if nutrientID.Valid {
+			total = &NutrientTotal{NutrientID: nutrientID.Int64, Name: nutrientName.String, Unit: unit, Amount: amount.Float64}
+		}
+		if isTotal {
+			if total != nil {
+				nutrition.Totals = append(nutrition.Totals, *total)
+			}
+			continue
+		}
+		if !ingredientID.Valid {
+			// the meal has no ingredients
+			continue
+		}
+
+		// rows are ordered by ingredient so each line is contiguous
+		last := len(nutrition.Ingredients) - 1
+		if last < 0 || nutrition.Ingredients[last].IngredientID != ingredientID.Int64 {
+			nutrition.Ingredients = append(nutrition.Ingredients, IngredientNutrition{
+				IngredientID:  ingredientID.Int64,
+				Name:          ingredientName.String,
+				AmountInGrams: quantity.Float64,
+			})
+			last++
+		}
+		if total != nil {
+			nutrition.Ingredients[last].Nutrients = append(nutrition.Ingredients[last].Nutrients, *total)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading meal nutrition: %w", err)
+	}
+	if !found {
+		return nil, ErrMealNotFound
+	}
+	return nutrition, nil
+}
+
+// mealNutritionRangeSQL returns one row per meal and nutrient. Meals without
+// any nutrient values still produce a single row with NULL nutrient columns.
+const mealNutritionRangeSQL = `
+SELECT
+    Meals.MealID,
+    Meals.Name,
+    Meals.Date,
+    Meals.Time,
+    Nutrients.NutrientID,
+    Nutrients.Name,
+    COALESCE(Nutrients.Unit, ''),
+    SUM(Nutrient_Values.AmountPer100g * Meal_Ingredients.QuantityInGrams / 100)
+FROM Meals
+LEFT JOIN Meal_Ingredients ON Meal_Ingredients.MealID = Meals.MealID
+LEFT JOIN Nutrient_Values ON Nutrient_Values.IngredientID = Meal_Ingredients.IngredientID
+LEFT JOIN Nutrients ON Nutrients.NutrientID = Nutrient_Values.NutrientID
+WHERE Meals.UserID = $1 AND Meals.Date >= $2 AND Meals.Date <= $3
+GROUP BY Meals.MealID, Meals.Name, Meals.Date, Meals.Time, Nutrients.NutrientID, Nutrients.Name, Nutrients.Unit
+ORDER BY Meals.Date, Meals.Time, Meals.MealID, Nutrients.Name
+`
+
+func (p *Postgres) ListMealNutrition(ctx context.Context, userID int64, from time.Time, to time.Time) ([]MealNutritionSummary, error) {
+	rows, err := p.db.QueryContext(ctx, mealNutritionRangeSQL, userID, from.Format(cursorDateLayout), to.Format(cursorDateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("querying meal nutrition: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []MealNutritionSummary
+	for rows.Next() {
+		var meal MealNutritionSummary
+		var nutrientID sql.NullInt64
+		var nutrientName sql.NullString
+		var unit string
+		var amount sql.NullFloat64
+		if err := rows.Scan(&meal.MealID, &meal.Name, &meal.Date, &meal.Time, &nutrientID, &nutrientName, &unit, &amount); err != nil {
+			return nil, fmt.Errorf("scanning meal nutrition: %w", err)
+		}
+
+		// rows are ordered by meal so each meal is contiguous
+		last := len(summaries) - 1
+		if last < 0 || summaries[last].MealID != meal.MealID {
+			summaries = append(summaries, meal)
+			last++
+		}
+		if nutrientID.Valid {
+			summaries[last].Totals = append(summaries[last].Totals, NutrientTotal{NutrientID: nutrientID.Int64, Name: nutrientName.String, Unit: unit, Amount: amount.Float64})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading meal nutrition: %w", err)
+	}
+	return summaries, nil
+}
+
+// mealExportSQL returns one row per meal, ingredient line and nutrient. The
+// LEFT JOINs keep meals without ingredients and lines without nutrient values.
+const mealExportSQL = `
+SELECT
+    Meals.MealID,
+    Meals.Name,
+    Meals.Date,
+    Meals.Time,
+    Meals.UpdatedAt,
+    Meal_Ingredients.IngredientID,
+    Ingredients.Name,
+    Meal_Ingredients.QuantityInGrams,
+    Nutrients.NutrientID,
+    Nutrients.Name,
+    COALESCE(Nutrients.Unit, ''),
+    Nutrient_Values.AmountPer100g * Meal_Ingredients.QuantityInGrams / 100
+FROM Meals
+LEFT JOIN Meal_Ingredients ON Meal_Ingredients.MealID = Meals.MealID
+LEFT JOIN Ingredients ON Ingredients.IngredientID = Meal_Ingredients.IngredientID
+LEFT JOIN Nutrient_Values ON Nutrient_Values.IngredientID = Meal_Ingredients.IngredientID
+LEFT JOIN Nutrients ON Nutrients.NutrientID = Nutrient_Values.NutrientID
+WHERE Meals.UserID = $1 AND Meals.Date >= $2 AND Meals.Date <= $3
+ORDER BY Meals.Date, Meals.Time, Meals.MealID, Meal_Ingredients.IngredientID, Nutrients.Name
+`
+
+// ExportMeals reads the export query row by row and hands each meal to fn as
+// soon as its last row has been read. The meal totals are summed here rather
+// than in SQL so that the rows can be streamed in a single pass.
+func (p *Postgres) ExportMeals(ctx context.Context, userID int64, from time.Time, to time.Time, fn func(*MealExport) error) error {
+	rows, err := p.db.QueryContext(ctx, mealExportSQL, userID, from.Format(cursorDateLayout), to.Format(cursorDateLayout))
+	if err != nil {
+		return fmt.Errorf("querying meal export: %w", err)
+	}
+	defer rows.Close()
+
+	var current *MealExport
+	var totals map[int64]*NutrientTotal
+	emit := func() error {
+		if current == nil {
+			return nil
+		}
+		current.Nutrition.Totals = sortedTotals(totals)
+		return fn(current)
+	}
+
+	for rows.Next() {
+		var meal Meal
+		var ingredientID, nutrientID sql.NullInt64
+		var ingredientName, nutrientName sql.NullString
+		var unit string
+		var quantity, amount sql.NullFloat64
+		if err := rows.Scan(&meal.MealID, &meal.Name, &meal.Date, &meal.Time, &meal.UpdatedAt, &ingredientID, &ingredientName, &quantity, &nutrientID, &nutrientName, &unit, &amount); err != nil {
+			return fmt.Errorf("scanning meal export: %w", err)
+		}
+
+		// rows are ordered by meal and then by ingredient
+		if current == nil || current.Meal.MealID != meal.MealID {
+			if err := emit(); err != nil {
+				return err
+			}
+			meal.UserID = userID
+			current = &MealExport{Meal: meal, Nutrition: MealNutrition{MealID: meal.MealID}}
+			totals = make(map[int64]*NutrientTotal)
+		}
+		if !ingredientID.Valid {
+			// the meal has no ingredients
+			continue
+		}
+
+		lines := current.Nutrition.Ingredients
+		if len(lines) == 0 || lines[len(lines)-1].IngredientID != ingredientID.Int64 {
+			current.Meal.Ingredients = append(current.Meal.Ingredients, MealIngredient{
+				IngredientID:  ingredientID.Int64,
+				Name:          ingredientName.String,
+				AmountInGrams: quantity.Float64,
+			})
+			current.Nutrition.Ingredients = append(lines, IngredientNutrition{
+				IngredientID:  ingredientID.Int64,
+				Name:          ingredientName.String,
+				AmountInGrams: quantity.Float64,
+			})
+		}
+		if nutrientID.Valid {
+			value := NutrientValue{NutrientID: nutrientID.Int64, Name: nutrientName.String, Unit: unit}
+			line := &current.Nutrition.Ingredients[len(current.Nutrition.Ingredients)-1]
+			line.Nutrients = append(line.Nutrients, NutrientTotal{NutrientID: value.NutrientID, Name: value.Name, Unit: value.Unit, Amount: amount.Float64})
+			addToTotals(totals, value, amount.Float64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading meal export: %w", err)
+	}
+	return emit()
+}
@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// deriveRecipeNutrientsSQL replaces the nutrient values of recipe $1 with
+// the sum of its ingredients' nutrients scaled to 100 g of the cooked yield.
+const deriveRecipeNutrientsSQL = `
+INSERT INTO Nutrient_Values (IngredientID, NutrientID, AmountPer100g)
+SELECT
+    Recipes.IngredientID,
+    Nutrient_Values.NutrientID,
+    SUM(Nutrient_Values.AmountPer100g * Recipe_Ingredients.QuantityInGrams / 100) * 100 / Recipes.YieldInGrams
+FROM Recipes
+INNER JOIN Recipe_Ingredients ON Recipe_Ingredients.RecipeID = Recipes.IngredientID
+INNER JOIN Nutrient_Values ON Nutrient_Values.IngredientID = Recipe_Ingredients.IngredientID
+WHERE Recipes.IngredientID = $1
+GROUP BY Recipes.IngredientID, Recipes.YieldInGrams, Nutrient_Values.NutrientID
+`
+
+func (p *Postgres) CreateRecipe(ctx context.Context, recipe *Recipe) (int64, error) {
+	var recipeID int64
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkIngredientName(ctx, tx, recipe.Name, 0); err != nil {
+			return err
+		}
+
+		err := tx.QueryRowContext(ctx, "INSERT INTO Ingredients (Name) VALUES ($1) RETURNING IngredientID", recipe.Name).Scan(&recipeID)
+		if err != nil {
+			return fmt.Errorf("inserting into Ingredients table: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO Recipes (IngredientID, YieldInGrams) VALUES ($1, $2)", recipeID, recipe.YieldInGrams)
+		if err != nil {
+			return fmt.Errorf("inserting into Recipes table: %w", err)
+		}
+
+		if err := insertRecipeIngredients(ctx, tx, recipeID, recipe.Ingredients); err != nil {
+			return err
+		}
+		return deriveRecipeNutrients(ctx, tx, recipeID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return recipeID, nil
+}
+
+func (p *Postgres) GetRecipe(ctx context.Context, recipeID int64) (*Recipe, error) {
+	return getRecipe(ctx, p.db, recipeID)
+}
+
+func (p *Postgres) ReplaceRecipe(ctx context.Context, recipe *Recipe) (*Recipe, error) {
+	var replaced *Recipe
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockRecipe(ctx, tx, recipe.RecipeID); err != nil {
+			return err
+		}
+		if err := checkIngredientName(ctx, tx, recipe.Name, recipe.RecipeID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "UPDATE Ingredients SET Name = $2, UpdatedAt = CURRENT_TIMESTAMP WHERE IngredientID = $1", recipe.RecipeID, recipe.Name)
+		if err != nil {
+			return fmt.Errorf("updating Ingredients table: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE Recipes SET YieldInGrams = $2, UpdatedAt = CURRENT_TIMESTAMP WHERE IngredientID = $1", recipe.RecipeID, recipe.YieldInGrams)
+		if err != nil {
+			return fmt.Errorf("updating Recipes table: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM Recipe_Ingredients WHERE RecipeID = $1", recipe.RecipeID)
+		if err != nil {
+			return fmt.Errorf("deleting from Recipe_Ingredients table: %w", err)
+		}
+
+		if err := insertRecipeIngredients(ctx, tx, recipe.RecipeID, recipe.Ingredients); err != nil {
+			return err
+		}
+		if err := deriveRecipeNutrients(ctx, tx, recipe.RecipeID); err != nil {
+			return err
+		}
+
+		replaced, err = getRecipe(ctx, tx, recipe.RecipeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+func (p *Postgres) DeleteRecipe(ctx context.Context, recipeID int64) (*Recipe, error) {
+	var deleted *Recipe
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockRecipe(ctx, tx, recipeID); err != nil {
+			return err
+		}
+		var err error
+		deleted, err = getRecipe(ctx, tx, recipeID)
+		if err != nil {
+			return err
+		}
+
+		// cascades to Recipes, Recipe_Ingredients, Nutrient_Values and the
+		// meals using the recipe
+		_, err = tx.ExecContext(ctx, "DELETE FROM Ingredients WHERE IngredientID = $1", recipeID)
+		if err != nil {
+			return fmt.Errorf("deleting from Ingredients table: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func getRecipe(ctx context.Context, q querier, recipeID int64) (*Recipe, error) {
+	var recipe Recipe
+	err := q.QueryRowContext(ctx, "SELECT Ingredients.IngredientID, Ingredients.Name, Recipes.YieldInGrams, Recipes.UpdatedAt FROM Recipes INNER JOIN Ingredients ON Ingredients.IngredientID = Recipes.IngredientID WHERE Recipes.IngredientID = $1", recipeID).Scan(&recipe.RecipeID, &recipe.Name, &recipe.YieldInGrams, &recipe.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecipeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying Recipes table: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT Recipe_Ingredients.IngredientID, Ingredients.Name, Recipe_Ingredients.QuantityInGrams FROM Recipe_Ingredients INNER JOIN Ingredients ON Ingredients.IngredientID = Recipe_Ingredients.IngredientID WHERE Recipe_Ingredients.RecipeID = $1 ORDER BY Ingredients.Name", recipeID)
+	if err != nil {
+		return nil, fmt.Errorf("querying Recipe_Ingredients table: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ingredient RecipeIngredient
+		if err := rows.Scan(&ingredient.IngredientID, &ingredient.Name, &ingredient.AmountInGrams); err != nil {
+			return nil, fmt.Errorf("scanning recipe ingredient: %w", err)
+		}
+		recipe.Ingredients = append(recipe.Ingredients, ingredient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading recipe ingredients: %w", err)
+	}
+	rows.Close()
+
+	recipe.Nutrients, err = listNutrientValues(ctx, q, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// lockRecipe locks the recipe row for the rest of the transaction.
+func lockRecipe(ctx context.Context, tx *sql.Tx, recipeID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT IngredientID FROM Recipes WHERE IngredientID = $1 FOR UPDATE", recipeID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecipeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("locking recipe: %w", err)
+	}
+	return nil
+}
+
+func insertRecipeIngredients(ctx context.Context, tx *sql.Tx, recipeID int64, ingredients []RecipeIngredient) error {
+	ids := make([]int64, len(ingredients))
+	for i, ingredient := range ingredients {
+		ids[i] = ingredient.IngredientID
+	}
+	var nested bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM Recipes WHERE IngredientID = ANY($1))", pq.Array(ids)).Scan(&nested)
+	if err != nil {
+		return fmt.Errorf("querying Recipes table: %w", err)
+	}
+	if nested {
+		return ErrNestedRecipe
+	}
+
+	for _, ingredient := range ingredients {
+		_, err := tx.ExecContext(ctx, "INSERT INTO Recipe_Ingredients (RecipeID, IngredientID, QuantityInGrams) VALUES ($1, $2, $3)", recipeID, ingredient.IngredientID, ingredient.AmountInGrams)
+		if isUniqueViolation(err) {
+			return ErrRecipeIngredientExists
+		}
+		if isForeignKeyViolation(err) {
+			return ErrIngredientNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("inserting into Recipe_Ingredients table: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkNotRecipe returns ErrIngredientIsRecipe if the ingredient is a recipe,
+// whose nutrient values must not be written directly.
+func checkNotRecipe(ctx context.Context, tx *sql.Tx, ingredientID int64) error {
+	var isRecipe bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM Recipes WHERE IngredientID = $1)", ingredientID).Scan(&isRecipe)
+	if err != nil {
+		return fmt.Errorf("querying Recipes table: %w", err)
+	}
+	if isRecipe {
+		return ErrIngredientIsRecipe
+	}
+	return nil
+}
+
+func deriveRecipeNutrients(ctx context.Context, tx *sql.Tx, recipeID int64) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM Nutrient_Values WHERE IngredientID = $1", recipeID)
+	if err != nil {
+		return fmt.Errorf("deleting from Nutrient_Values table: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, deriveRecipeNutrientsSQL, recipeID); err != nil {
+		return fmt.Errorf("deriving recipe nutrient values: %w", err)
+	}
+	return nil
+}
+
+// deriveRecipesUsing derives the nutrient values of every recipe containing
+// the ingredient again after its own values changed.
+func deriveRecipesUsing(ctx context.Context, tx *sql.Tx, ingredientID int64) error {
+	rows, err := tx.QueryContext(ctx, "SELECT RecipeID FROM Recipe_Ingredients WHERE IngredientID = $1 ORDER BY RecipeID", ingredientID)
+	if err != nil {
+		return fmt.Errorf("querying Recipe_Ingredients table: %w", err)
+	}
+	var recipeIDs []int64
+	for rows.Next() {
+		var recipeID int64
+		if err := rows.Scan(&recipeID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning recipe ID: %w", err)
+		}
+		recipeIDs = append(recipeIDs, recipeID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading recipe IDs: %w", err)
+	}
+
+	for _, recipeID := range recipeIDs {
+		if err := deriveRecipeNutrients(ctx, tx, recipeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// timeoutError is a net.Error that does not come from a connection, like
+// the timeout of an HTTP client.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no error", nil, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"closed connection", fmt.Errorf("begin: %w", sql.ErrConnDone), true},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"shutdown", &pq.Error{Code: "57P01"}, true},
+		{"too many connections", &pq.Error{Code: "53300"}, true},
+		{"connection exception", &pq.Error{Code: "08006"}, true},
+		{"canceled", context.Canceled, false},
+		{"timed out", fmt.Errorf("pq: %w", context.DeadlineExceeded), false},
+		{"timed out while dialing", &net.OpError{Op: "dial", Net: "tcp", Err: context.DeadlineExceeded}, false},
+		{"other network error", timeoutError{}, false},
+		{"end of input", io.EOF, false},
+		{"truncated input", io.ErrUnexpectedEOF, false},
+		{"query canceled", &pq.Error{Code: "57014"}, false},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"anything else", errors.New("boom"), false},
+	}
+	for _, test := range tests {
+		if got := Unavailable(test.err); got != test.want {
+			t.Errorf("Unavailable(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOutOfRange(t *testing.T) {
+	if !OutOfRange(fmt.Errorf("insert: %w", &pq.Error{Code: "22003"})) {
+		t.Error("OutOfRange of numeric_value_out_of_range = false")
+	}
+	if OutOfRange(&pq.Error{Code: "23514"}) {
+		t.Error("OutOfRange of check_violation = true")
+	}
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (p *Postgres) CreateUser(ctx context.Context, user *User) (int64, error) {
+	var userID int64
+	err := p.db.QueryRowContext(ctx, "INSERT INTO Users (Username, Email, PasswordHash) VALUES ($1, $2, $3) RETURNING UserID", user.Username, user.Email, user.PasswordHash).Scan(&userID)
+	if isUniqueViolation(err) {
+		return 0, ErrUserExists
+	}
+	if err != nil {
+		return 0, fmt.Errorf("inserting into Users table: %w", err)
+	}
+	return userID, nil
+}
+
+func (p *Postgres) GetUser(ctx context.Context, userID int64) (*User, error) {
+	return p.getUser(ctx, "SELECT UserID, Username, Email, PasswordHash, CreatedAt FROM Users WHERE UserID = $1", userID)
+}
+
+func (p *Postgres) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return p.getUser(ctx, "SELECT UserID, Username, Email, PasswordHash, CreatedAt FROM Users WHERE Username = $1", username)
+}
+
+func (p *Postgres) getUser(ctx context.Context, query string, arg any) (*User, error) {
+	var user User
+	err := p.db.QueryRowContext(ctx, query, arg).Scan(&user.UserID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying Users table: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,363 @@
+// Package store contains the persistence layer used by the HTTP handlers.
+// Handlers depend only on the interfaces declared here so the same logic can
+// be reused from jobs, CLIs and tests.
+package store
+
+import (
+	"assignment2/catalog"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMealNotFound           = errors.New("meal not found")
+	ErrMealModified           = errors.New("meal was modified concurrently")
+	ErrMealIngredientNotFound = errors.New("ingredient is not part of the meal")
+	ErrMealIngredientExists   = errors.New("ingredient is already part of the meal")
+	ErrIngredientNotFound     = errors.New("ingredient not found")
+	ErrNutrientNotFound       = errors.New("nutrient not found")
+	ErrIngredientExists       = errors.New("ingredient already exists")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrUserExists             = errors.New("username or email already taken")
+	ErrGoalNotFound           = errors.New("goal not found")
+	ErrRecipeNotFound         = errors.New("recipe not found")
+	ErrRecipeIngredientExists = errors.New("ingredient is already part of the recipe")
+	ErrNestedRecipe           = errors.New("recipes cannot contain other recipes")
+	ErrIngredientIsRecipe     = errors.New("nutrients of a recipe are derived from its ingredients")
+	ErrIngredientInUse        = errors.New("ingredient is used by a recipe")
+	ErrBarcodeExists          = errors.New("barcode is already used by another ingredient")
+)
+
+type User struct {
+	UserID       int64
+	Username     string
+	Email        string
+	PasswordHash string
+	CreatedAt    time.Time
+}
+
+type Meal struct {
+	MealID      int64
+	UserID      int64
+	Name        string
+	Date        time.Time
+	Time        time.Time
+	UpdatedAt   time.Time
+	Ingredients []MealIngredient
+}
+
+// NutrientTotal is the amount of a nutrient contained in a quantity of food,
+// in the nutrient's own unit.
+type NutrientTotal struct {
+	NutrientID int64
+	Name       string
+	Unit       string
+	Amount     float64
+}
+
+// IngredientNutrition is the nutrient breakdown of one meal ingredient line.
+type IngredientNutrition struct {
+	IngredientID  int64
+	Name          string
+	AmountInGrams float64
+	Nutrients     []NutrientTotal
+}
+
+// MealNutrition holds the nutrient totals of a meal and the contribution of
+// each ingredient line.
+type MealNutrition struct {
+	MealID      int64
+	Totals      []NutrientTotal
+	Ingredients []IngredientNutrition
+}
+
+// MealExport is one meal of a diary export: the meal with its ingredient
+// lines and the nutrient breakdown of those lines.
+type MealExport struct {
+	Meal      Meal
+	Nutrition MealNutrition
+}
+
+// MealNutritionSummary holds the nutrient totals of one meal without the
+// per-ingredient breakdown.
+type MealNutritionSummary struct {
+	MealID int64
+	Name   string
+	Date   time.Time
+	Time   time.Time
+	Totals []NutrientTotal
+}
+
+// MealUpdate lists the meal fields to change; nil fields are left alone.
+type MealUpdate struct {
+	Name     *string
+	DateTime *time.Time
+}
+
+// MealIngredient is a single line of a meal. Name is only populated when the
+// line is read back from the store.
+type MealIngredient struct {
+	IngredientID  int64
+	Name          string
+	AmountInGrams float64
+}
+
+// Ingredient is a generic ingredient or, when Barcode is set, a packaged
+// product. Barcode is a normalised 14 digit GTIN and unique across
+// ingredients; Brand is optional.
+type Ingredient struct {
+	IngredientID int64
+	Name         string
+	Barcode      string
+	Brand        string
+	Nutrients    []NutrientValue
+}
+
+// NutrientValue is the amount of a nutrient in 100 grams of an ingredient.
+// Unit is only populated when the value is read back from the store.
+type NutrientValue struct {
+	NutrientID    int64
+	Name          string
+	Unit          string
+	AmountPer100g float64
+}
+
+// Nutrient is a row of the Nutrients table. Unit and Category are empty for
+// rows created before the catalog existed that could not be matched to it.
+type Nutrient struct {
+	NutrientID int64
+	Name       string
+	Unit       string
+	Category   string
+}
+
+// UpsertSummary counts what UpsertIngredients did with each ingredient.
+type UpsertSummary struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Skipped   int
+}
+
+// Recipe is a dish made of ingredients. It is stored as an ingredient with
+// RecipeID as its IngredientID so meals can use it like any other, and its
+// Nutrients are derived from the ingredient quantities and YieldInGrams, the
+// weight of the finished dish. Nutrients and UpdatedAt are only populated
+// when the recipe is read back from the store.
+type Recipe struct {
+	RecipeID     int64
+	Name         string
+	YieldInGrams float64
+	Ingredients  []RecipeIngredient
+	Nutrients    []NutrientValue
+	UpdatedAt    time.Time
+}
+
+// RecipeIngredient is a single line of a recipe. Name is only populated when
+// the line is read back from the store.
+type RecipeIngredient struct {
+	IngredientID  int64
+	Name          string
+	AmountInGrams float64
+}
+
+// Goal is a user's daily target for one nutrient, in the nutrient's unit.
+// At least one of Min and Max is set.
+type Goal struct {
+	NutrientID int64
+	Name       string
+	Unit       string
+	Min        *float64
+	Max        *float64
+	UpdatedAt  time.Time
+}
+
+// Sort orders accepted by ListMeals; a leading "-" means descending.
+const (
+	MealSortDate     = "date"
+	MealSortDateDesc = "-date"
+)
+
+// MealFilter selects a page of a user's meals. From and To are inclusive
+// dates and are ignored when zero.
+type MealFilter struct {
+	From   time.Time
+	To     time.Time
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// MealPage is one page of meals. NextCursor is empty on the last page.
+type MealPage struct {
+	Meals      []Meal
+	NextCursor string
+}
+
+// Sort orders accepted by ListIngredients; a leading "-" means descending.
+const (
+	IngredientSortName     = "name"
+	IngredientSortNameDesc = "-name"
+	IngredientSortID       = "id"
+	IngredientSortIDDesc   = "-id"
+)
+
+// IngredientFilter selects a page of ingredients. NamePrefix matches names
+// case-insensitively and HasNutrient keeps only ingredients with a value for
+// the named nutrient.
+type IngredientFilter struct {
+	NamePrefix  string
+	HasNutrient string
+	Sort        string
+	Limit       int
+	Cursor      string
+}
+
+// IngredientPage is one page of ingredients. NextCursor is empty on the last
+// page.
+type IngredientPage struct {
+	Ingredients []Ingredient
+	NextCursor  string
+}
+
+// MealStore methods that take a userID only see meals owned by that user;
+// other users' meals are reported as ErrMealNotFound.
+type MealStore interface {
+	// CreateMeal inserts the meal, owned by meal.UserID, together with its
+	// ingredient lines and returns the new meal ID. Ingredient lines fail the
+	// same way as in AddIngredientToMeal.
+	CreateMeal(ctx context.Context, meal *Meal) (int64, error)
+	GetMeal(ctx context.Context, userID int64, mealID int64) (*Meal, error)
+	// GetMealNutrition computes the nutrient totals of a meal from its
+	// ingredient quantities and their per 100 g nutrient values.
+	GetMealNutrition(ctx context.Context, userID int64, mealID int64) (*MealNutrition, error)
+	// ListMealNutrition returns the nutrient totals of every meal of the user
+	// dated between from and to inclusive, ordered by date and time.
+	ListMealNutrition(ctx context.Context, userID int64, from time.Time, to time.Time) ([]MealNutritionSummary, error)
+	// ExportMeals calls fn for every meal of the user dated between from and
+	// to inclusive, ordered by date and time. Meals are passed on while the
+	// result is still being read, so the whole range is never held in
+	// memory. An error returned by fn stops the export and is returned.
+	ExportMeals(ctx context.Context, userID int64, from time.Time, to time.Time, fn func(*MealExport) error) error
+	// ListMeals returns a page of the user's meals with their ingredient
+	// lines, or ErrInvalidCursor if filter.Cursor was not issued for
+	// filter.Sort.
+	ListMeals(ctx context.Context, userID int64, filter MealFilter) (*MealPage, error)
+	// AddIngredientToMeal adds a new ingredient line. It fails with
+	// ErrMealIngredientExists if the meal already has the ingredient and with
+	// ErrIngredientNotFound if the ingredient does not exist.
+	AddIngredientToMeal(ctx context.Context, userID int64, mealID int64, ingredient MealIngredient) error
+	// SetMealIngredient sets the quantity of an ingredient line, adding the
+	// line if the meal does not have it yet. created reports which happened.
+	SetMealIngredient(ctx context.Context, userID int64, mealID int64, ingredient MealIngredient) (created bool, err error)
+
+	// The methods below take ifUpdatedAt for optimistic concurrency: when it
+	// is non-zero and differs from the meal's UpdatedAt they fail with
+	// ErrMealModified and change nothing.
+
+	// UpdateMeal applies update and returns the updated meal.
+	UpdateMeal(ctx context.Context, userID int64, mealID int64, update MealUpdate, ifUpdatedAt time.Time) (*Meal, error)
+	// RemoveIngredientFromMeal deletes one ingredient line and returns the
+	// updated meal, or ErrMealIngredientNotFound if the line does not exist.
+	RemoveIngredientFromMeal(ctx context.Context, userID int64, mealID int64, ingredientID int64, ifUpdatedAt time.Time) (*Meal, error)
+	// DeleteMeal deletes the meal and its ingredient lines and returns the
+	// meal as it was before deletion.
+	DeleteMeal(ctx context.Context, userID int64, mealID int64, ifUpdatedAt time.Time) (*Meal, error)
+}
+
+type IngredientStore interface {
+	// CreateIngredient inserts the ingredient and its nutrient values.
+	// Nutrients are matched by their canonical catalog name; any other name
+	// fails with ErrNutrientNotFound. ReplaceIngredient and
+	// SetIngredientNutrients match nutrients the same way. A barcode already
+	// in use fails with ErrBarcodeExists.
+	CreateIngredient(ctx context.Context, ingredient *Ingredient) (int64, error)
+	GetIngredient(ctx context.Context, ingredientID int64) (*Ingredient, error)
+	// GetIngredientByBarcode returns the ingredient with the given normalised
+	// GTIN, or ErrIngredientNotFound.
+	GetIngredientByBarcode(ctx context.Context, barcode string) (*Ingredient, error)
+	// ListIngredients returns a page of ingredients with their nutrient
+	// values, or ErrInvalidCursor if filter.Cursor was not issued for
+	// filter.Sort.
+	ListIngredients(ctx context.Context, filter IngredientFilter) (*IngredientPage, error)
+	// ReplaceIngredient renames the ingredient, sets its barcode and brand and
+	// replaces its complete nutrient list in one transaction, returning the
+	// stored result. Recipes using the ingredient are updated as well.
+	//
+	// Both ReplaceIngredient and SetIngredientNutrients fail with
+	// ErrIngredientIsRecipe for recipes.
+	ReplaceIngredient(ctx context.Context, ingredient *Ingredient) (*Ingredient, error)
+	// SetIngredientNutrients inserts or updates the given nutrient values and
+	// leaves the ingredient's other nutrients untouched.
+	SetIngredientNutrients(ctx context.Context, ingredientID int64, nutrients []NutrientValue) (*Ingredient, error)
+	// UpsertIngredients creates or updates a batch of ingredients matched by
+	// barcode, or by name when no barcode is given, in one transaction.
+	// Existing ingredients get the given nutrient values inserted or updated
+	// and keep their other values. Recipes are skipped, and so are products
+	// whose name is already taken by a different ingredient.
+	UpsertIngredients(ctx context.Context, ingredients []Ingredient) (*UpsertSummary, error)
+	// DeleteIngredient removes the ingredient and returns what was deleted,
+	// or fails with ErrIngredientInUse while a recipe contains it.
+	DeleteIngredient(ctx context.Context, ingredientID int64) (*Ingredient, error)
+}
+
+type NutrientStore interface {
+	// GetNutrientByName returns the catalog nutrient with the given canonical
+	// name.
+	GetNutrientByName(ctx context.Context, name string) (*Nutrient, error)
+	ListNutrients(ctx context.Context) ([]Nutrient, error)
+	// SyncNutrientCatalog makes sure every catalog nutrient has a row with its
+	// unit and category. Existing rows whose names are aliases of a catalog
+	// nutrient are merged into it, moving their nutrient values along.
+	SyncNutrientCatalog(ctx context.Context, nutrients []catalog.Nutrient) error
+}
+
+type UserStore interface {
+	// CreateUser inserts the user and returns the new user ID, or
+	// ErrUserExists when the username or email is already taken.
+	CreateUser(ctx context.Context, user *User) (int64, error)
+	GetUser(ctx context.Context, userID int64) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+}
+
+// RecipeStore writes fail with ErrIngredientNotFound when an ingredient line
+// does not exist, ErrNestedRecipe when it is itself a recipe and
+// ErrRecipeIngredientExists when it is listed twice.
+type RecipeStore interface {
+	// CreateRecipe inserts the recipe and its derived nutrient values and
+	// returns the new recipe ID. It fails with ErrIngredientExists when an
+	// ingredient already has the name.
+	CreateRecipe(ctx context.Context, recipe *Recipe) (int64, error)
+	GetRecipe(ctx context.Context, recipeID int64) (*Recipe, error)
+	// ReplaceRecipe replaces the name, yield and ingredient lines and derives
+	// the nutrient values again.
+	ReplaceRecipe(ctx context.Context, recipe *Recipe) (*Recipe, error)
+	// DeleteRecipe removes the recipe along with its ingredient row and
+	// returns what was deleted.
+	DeleteRecipe(ctx context.Context, recipeID int64) (*Recipe, error)
+}
+
+// GoalStore methods only see the goals of the given user.
+type GoalStore interface {
+	// ListGoals returns the user's goals ordered by nutrient name.
+	ListGoals(ctx context.Context, userID int64) ([]Goal, error)
+	// SetGoal inserts or replaces the goal for the catalog nutrient named
+	// goal.Name and reports whether it was created. It fails with
+	// ErrNutrientNotFound if the nutrient is not in the catalog.
+	SetGoal(ctx context.Context, userID int64, goal Goal) (*Goal, bool, error)
+	// DeleteGoal removes the goal for the named nutrient, or fails with
+	// ErrGoalNotFound.
+	DeleteGoal(ctx context.Context, userID int64, nutrientName string) error
+}
+
+// Store is implemented by every backend and bundles all of the interfaces
+// above.
+type Store interface {
+	MealStore
+	IngredientStore
+	NutrientStore
+	UserStore
+	GoalStore
+	RecipeStore
+}